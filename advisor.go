@@ -0,0 +1,239 @@
+package mongodb
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// IndexAdvice 索引建议, 来自实际执行过的查询形状
+type IndexAdvice struct {
+	Collection string
+	// Equality 等值条件字段
+	Equality []string
+	// Range 范围条件字段
+	Range []string
+	Sort  bson.D
+	// Count 该查询形状出现的次数
+	Count int64
+	// CollScan 获胜计划为全表扫描
+	CollScan bool
+	// InMemorySort 排序没有走索引
+	InMemorySort bool
+	// Plan 执行计划阶段
+	Plan string
+	// Suggested 按 等值-排序-范围 顺序建议的索引
+	Suggested bson.D
+}
+
+type queryShape struct {
+	equality []string
+	ranges   []string
+	sort     bson.D
+}
+
+// key 查询形状的唯一标识, 与字段值无关
+func (shape queryShape) key() string {
+	var b strings.Builder
+	b.WriteString(strings.Join(shape.equality, ","))
+	b.WriteString("|")
+	b.WriteString(strings.Join(shape.ranges, ","))
+	b.WriteString("|")
+	for _, e := range shape.sort {
+		b.WriteString(e.Key)
+		b.WriteString(":")
+		if toInt64(e.Value) < 0 {
+			b.WriteString("-1,")
+		} else {
+			b.WriteString("1,")
+		}
+	}
+	return b.String()
+}
+
+type shapeEntry struct {
+	advice   IndexAdvice
+	analyzed bool
+	// analyzing 后台 explain 进行中, 失败后清除, 下次出现该形状时重试
+	analyzing bool
+}
+
+// indexAdvisor 记录每个集合的查询形状并分析执行计划
+type indexAdvisor struct {
+	mu     sync.Mutex
+	shapes map[string]map[string]*shapeEntry
+}
+
+func newIndexAdvisor() *indexAdvisor {
+	return &indexAdvisor{shapes: make(map[string]map[string]*shapeEntry)}
+}
+
+// observe 记录一次查询, 还没有分析过的形状在后台 explain, 成功后不再重复
+func (query *Query) observe(method string) {
+	opt := query.client.opt
+	advisor := query.client.advisor
+	if advisor == nil || opt == nil || !opt.IndexAdvisor || !explainable[method] {
+		return
+	}
+	shape := shapeOf(query.filter, query.sort)
+	namespace := query.namespace()
+	key := shape.key()
+
+	advisor.mu.Lock()
+	shapes, ok := advisor.shapes[namespace]
+	if !ok {
+		shapes = make(map[string]*shapeEntry)
+		advisor.shapes[namespace] = shapes
+	}
+	entry, ok := shapes[key]
+	if !ok {
+		entry = &shapeEntry{advice: IndexAdvice{
+			Collection: namespace,
+			Equality:   shape.equality,
+			Range:      shape.ranges,
+			Sort:       shape.sort,
+		}}
+		shapes[key] = entry
+	}
+	entry.advice.Count++
+	start := !entry.analyzed && !entry.analyzing
+	entry.analyzing = true
+	advisor.mu.Unlock()
+	if !start {
+		return
+	}
+
+	go func() {
+		plan, err := query.explain()
+		advisor.mu.Lock()
+		defer advisor.mu.Unlock()
+		entry.analyzing = false
+		if err != nil {
+			if Log != nil {
+				Log.Warn("MongoDB索引分析explain失败->", namespace, " ", err)
+			}
+			return
+		}
+		stages := strings.Split(plan.Stages, " <- ")
+		entry.analyzed = true
+		entry.advice.Plan = plan.Stages
+		for _, stage := range stages {
+			switch stage {
+			case "COLLSCAN":
+				entry.advice.CollScan = true
+			case "SORT":
+				entry.advice.InMemorySort = true
+			}
+		}
+		if entry.advice.CollScan || entry.advice.InMemorySort {
+			entry.advice.Suggested = shape.suggest()
+		}
+	}()
+}
+
+// report 返回存在全表扫描或内存排序的查询形状, 出现次数多的在前
+func (advisor *indexAdvisor) report() []IndexAdvice {
+	if advisor == nil {
+		return nil
+	}
+	advisor.mu.Lock()
+	defer advisor.mu.Unlock()
+	var advices []IndexAdvice
+	for _, shapes := range advisor.shapes {
+		for _, entry := range shapes {
+			if entry.analyzed && (entry.advice.CollScan || entry.advice.InMemorySort) {
+				advices = append(advices, entry.advice)
+			}
+		}
+	}
+	sort.Slice(advices, func(i, j int) bool {
+		if advices[i].Count != advices[j].Count {
+			return advices[i].Count > advices[j].Count
+		}
+		return advices[i].Collection < advices[j].Collection
+	})
+	return advices
+}
+
+// suggest 按 ESR(等值-排序-范围) 规则生成索引
+func (shape queryShape) suggest() bson.D {
+	keys := bson.D{}
+	seen := make(map[string]bool)
+	add := func(field string, direction interface{}) {
+		if seen[field] {
+			return
+		}
+		seen[field] = true
+		keys = append(keys, bson.E{Key: field, Value: direction})
+	}
+	for _, field := range shape.equality {
+		add(field, 1)
+	}
+	for _, e := range shape.sort {
+		add(e.Key, e.Value)
+	}
+	for _, field := range shape.ranges {
+		add(field, 1)
+	}
+	return keys
+}
+
+// shapeOf 提取查询条件中的等值字段、范围字段和排序
+func shapeOf(filter bson.D, sorts bson.D) queryShape {
+	shape := queryShape{sort: sorts}
+	collectShape(filter, &shape)
+	sort.Strings(shape.equality)
+	sort.Strings(shape.ranges)
+	return shape
+}
+
+func collectShape(filter bson.D, shape *queryShape) {
+	for _, e := range filter {
+		if e.Key == "$and" {
+			if clauses, ok := e.Value.(bson.A); ok {
+				for _, clause := range clauses {
+					if d, ok := clause.(bson.D); ok {
+						collectShape(d, shape)
+					}
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(e.Key, "$") {
+			continue
+		}
+		if isRangeCondition(e.Value) {
+			shape.ranges = append(shape.ranges, e.Key)
+		} else {
+			shape.equality = append(shape.equality, e.Key)
+		}
+	}
+}
+
+// isRangeCondition 条件中包含 $eq/$in 以外的操作符时视为范围条件
+func isRangeCondition(v interface{}) bool {
+	var operators []string
+	switch cond := v.(type) {
+	case bson.D:
+		for _, e := range cond {
+			operators = append(operators, e.Key)
+		}
+	case bson.M:
+		for k := range cond {
+			operators = append(operators, k)
+		}
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(op, "$") && op != "$eq" && op != "$in" {
+			return true
+		}
+	}
+	return false
+}
+
+// IndexAdvice 获取索引建议, 需要在 Opt 中开启 IndexAdvisor
+func (configs *Configs) IndexAdvice() []IndexAdvice {
+	return configs.advisor.report()
+}
@@ -0,0 +1,65 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// accumulate 对满足条件的文档的 field 执行 $sum/$avg/$min/$max, 没有文档时返回 mongo.ErrNoDocuments
+func (query *Query) accumulate(ctx context.Context, method, operator, field string) (value float64, err error) {
+	err = query.do(ctx, method, func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, bson.A{
+			bson.D{{Key: "$match", Value: query.filter}},
+			bson.D{{Key: "$group", Value: bson.D{
+				{Key: "_id", Value: nil},
+				{Key: "value", Value: bson.D{{Key: operator, Value: "$" + field}}},
+			}}},
+		})
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{Collation: query.collation, Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		var results []struct {
+			Value interface{} `bson:"value"`
+		}
+		if err := cursor.All(ctx, &results); err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return mongo.ErrNoDocuments
+		}
+		value = toFloat64(results[0].Value)
+		return nil
+	})
+	return
+}
+
+// Sum 满足条件的文档 field 字段之和, 没有文档时为 0
+func (query *Query) Sum(ctx context.Context, field string) (float64, error) {
+	sum, err := query.accumulate(ctx, "Sum", "$sum", field)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	return sum, err
+}
+
+// Avg 满足条件的文档 field 字段平均值, 没有文档时返回 mongo.ErrNoDocuments
+func (query *Query) Avg(ctx context.Context, field string) (float64, error) {
+	return query.accumulate(ctx, "Avg", "$avg", field)
+}
+
+// Min 满足条件的文档 field 字段最小值, 没有文档时返回 mongo.ErrNoDocuments
+func (query *Query) Min(ctx context.Context, field string) (float64, error) {
+	return query.accumulate(ctx, "Min", "$min", field)
+}
+
+// Max 满足条件的文档 field 字段最大值, 没有文档时返回 mongo.ErrNoDocuments
+func (query *Query) Max(ctx context.Context, field string) (float64, error) {
+	return query.accumulate(ctx, "Max", "$max", field)
+}
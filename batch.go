@@ -0,0 +1,232 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultBatchSize DeleteInBatches / UpdateInBatches 默认每批文档数
+const DefaultBatchSize = 1000
+
+// DeleteInBatches 分批删除满足条件的文档, 每批最多 batchSize 条, 批次之间休眠 sleep,
+// 每批单独计算超时, 避免大量删除长时间占用主节点. 返回已删除的文档数
+func (query *Query) DeleteInBatches(ctx context.Context, batchSize int64, sleep time.Duration) (deleted int64, err error) {
+	if len(query.where) == 0 {
+		return 0, errors.New("you can't delete all documents, it's very dangerous")
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		var ids bson.A
+		err = query.do(ctx, "DeleteInBatches", func(ctx context.Context) error {
+			cursor, err := query.Table.Find(ctx, query.filter, &options.FindOptions{
+				Projection: bson.D{{Key: "_id", Value: 1}},
+				Limit:      &batchSize,
+				Collation:  query.collation,
+				Comment:    commentOf(ctx),
+			})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				ids = append(ids, cursor.Current.Lookup("_id"))
+			}
+			return cursor.Err()
+		})
+		if err != nil || len(ids) == 0 {
+			return
+		}
+		var count int64
+		err = query.do(ctx, "DeleteInBatches", func(ctx context.Context) error {
+			result, err := query.Table.DeleteMany(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}, options.Delete().SetComment(commentValue(ctx)))
+			if err != nil {
+				return err
+			}
+			count = result.DeletedCount
+			return nil
+		})
+		deleted += count
+		if err != nil {
+			return
+		}
+		if Log != nil {
+			Log.Debug("MongoDB分批删除->", query.namespace(), " ", deleted, labelSuffix(query.client.Labels()))
+		}
+		if int64(len(ids)) < batchSize {
+			return
+		}
+		if sleep > 0 {
+			select {
+			case <-ctx.Done():
+				return deleted, ctx.Err()
+			case <-time.After(sleep):
+			}
+		}
+	}
+}
+
+// BatchUpdateOptions UpdateInBatches / TransformInBatches 运行参数
+type BatchUpdateOptions struct {
+	// BatchSize 每批文档数, 默认 DefaultBatchSize
+	BatchSize int64
+	// Sleep 批次之间的休眠时间
+	Sleep time.Duration
+	// Checkpoint 断点存储, 每批成功后保存最后一个 _id, 重新运行时从断点继续
+	Checkpoint Checkpointer
+	// Progress 每批完成后回调
+	Progress func(BatchUpdateProgress)
+}
+
+// BatchUpdateProgress 分批更新进度
+type BatchUpdateProgress struct {
+	Batches  int64
+	Scanned  int64
+	Matched  int64
+	Modified int64
+	LastID   interface{}
+}
+
+// UpdateInBatches 按 _id 升序分批遍历满足条件的文档, 对每批执行原始更新语句 update
+// (如 {"$set": ...}), 适合大集合的数据回填
+func (query *Query) UpdateInBatches(ctx context.Context, update interface{}, opts *BatchUpdateOptions) (BatchUpdateProgress, error) {
+	if err := query.checkShardUpdate(nil, update); err != nil {
+		return BatchUpdateProgress{}, err
+	}
+	projection := bson.D{{Key: "_id", Value: 1}}
+	return query.walkBatches(ctx, "UpdateInBatches", projection, opts, func(ctx context.Context, docs []bson.Raw) (*mongo.BulkWriteResult, error) {
+		ids := make(bson.A, 0, len(docs))
+		for _, doc := range docs {
+			ids = append(ids, doc.Lookup("_id"))
+		}
+		filter := bson.D{{Key: "$and", Value: bson.A{query.filter, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}}}}
+		result, err := query.Table.UpdateMany(ctx, filter, update, options.Update().SetComment(commentValue(ctx)))
+		if err != nil {
+			return nil, err
+		}
+		return &mongo.BulkWriteResult{MatchedCount: result.MatchedCount, ModifiedCount: result.ModifiedCount}, nil
+	})
+}
+
+// TransformInBatches 按 _id 升序分批遍历满足条件的文档, transform 为每条文档返回原始更新语句,
+// 返回 nil 表示跳过该文档
+func (query *Query) TransformInBatches(ctx context.Context, transform func(doc bson.M) (update interface{}, err error), opts *BatchUpdateOptions) (BatchUpdateProgress, error) {
+	return query.walkBatches(ctx, "TransformInBatches", query.projection(ctx), opts, func(ctx context.Context, docs []bson.Raw) (*mongo.BulkWriteResult, error) {
+		models := make([]mongo.WriteModel, 0, len(docs))
+		for _, raw := range docs {
+			var doc bson.M
+			if err := query.decode(raw, &doc); err != nil {
+				return nil, err
+			}
+			update, err := transform(doc)
+			if err != nil {
+				return nil, err
+			}
+			if update == nil {
+				continue
+			}
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(bson.D{{Key: "_id", Value: doc["_id"]}}).
+				SetUpdate(update))
+		}
+		if len(models) == 0 {
+			return &mongo.BulkWriteResult{}, nil
+		}
+		return query.Table.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false).SetComment(commentValue(ctx)))
+	})
+}
+
+// walkBatches 按 _id 范围分批读取文档并交给 apply 处理, 负责断点、进度和限速
+func (query *Query) walkBatches(ctx context.Context, method string, projection interface{}, opts *BatchUpdateOptions, apply func(ctx context.Context, docs []bson.Raw) (*mongo.BulkWriteResult, error)) (BatchUpdateProgress, error) {
+	var progress BatchUpdateProgress
+	if opts == nil {
+		opts = &BatchUpdateOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	if opts.Checkpoint != nil {
+		lastID, err := opts.Checkpoint.Load(ctx)
+		if err != nil {
+			return progress, err
+		}
+		progress.LastID = lastID
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+		filter := query.filter
+		if progress.LastID != nil {
+			filter = bson.D{{Key: "$and", Value: bson.A{query.filter, bson.D{{Key: "_id", Value: bson.D{{Key: "$gt", Value: progress.LastID}}}}}}}
+		}
+		var docs []bson.Raw
+		err := query.do(ctx, method, func(ctx context.Context) error {
+			cursor, err := query.Table.Find(ctx, filter, &options.FindOptions{
+				Projection: projection,
+				Sort:       bson.D{{Key: "_id", Value: 1}},
+				Limit:      &batchSize,
+				Collation:  query.collation,
+				Comment:    commentOf(ctx),
+			})
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			for cursor.Next(ctx) {
+				docs = append(docs, append(bson.Raw(nil), cursor.Current...))
+			}
+			return cursor.Err()
+		})
+		if err != nil || len(docs) == 0 {
+			return progress, err
+		}
+		var result *mongo.BulkWriteResult
+		err = query.do(ctx, method, func(ctx context.Context) (err error) {
+			result, err = apply(ctx, docs)
+			return
+		})
+		if result != nil {
+			progress.Matched += result.MatchedCount
+			progress.Modified += result.ModifiedCount
+		}
+		if err != nil {
+			return progress, err
+		}
+		progress.Batches++
+		progress.Scanned += int64(len(docs))
+		var lastID interface{}
+		if err := unmarshalValue(docs[len(docs)-1].Lookup("_id"), &lastID); err != nil {
+			return progress, err
+		}
+		progress.LastID = lastID
+		if opts.Checkpoint != nil {
+			if err := opts.Checkpoint.Save(ctx, lastID); err != nil {
+				return progress, err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+		if int64(len(docs)) < batchSize {
+			return progress, nil
+		}
+		if opts.Sleep > 0 {
+			select {
+			case <-ctx.Done():
+				return progress, ctx.Err()
+			case <-time.After(opts.Sleep):
+			}
+		}
+	}
+}
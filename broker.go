@@ -0,0 +1,133 @@
+package mongodb
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker 在一个变更流上向多个订阅者分发事件, 节省服务端游标
+type Broker struct {
+	query  *Query
+	buffer int
+
+	mu      sync.Mutex
+	subs    map[*Subscription]bool
+	closing []*Subscription
+	closed  bool
+}
+
+// Subscription 一个订阅, 通过 C 接收匹配的事件, 取消订阅或 Broker 停止后 C 被关闭
+type Subscription struct {
+	C      <-chan ChangeEvent
+	ch     chan ChangeEvent
+	done   chan struct{}
+	match  func(*ChangeEvent) bool
+	broker *Broker
+}
+
+// NewBroker 创建变更流分发器, buffer 为每个订阅者的缓冲区大小
+func NewBroker(query *Query, buffer int) *Broker {
+	return &Broker{query: query, buffer: buffer, subs: make(map[*Subscription]bool)}
+}
+
+// OperationTypes 按操作类型(insert/update/replace/delete...)过滤事件
+func OperationTypes(types ...string) func(*ChangeEvent) bool {
+	set := make(map[string]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(event *ChangeEvent) bool {
+		return set[event.OperationType]
+	}
+}
+
+// Subscribe 订阅满足 match 的事件, match 为 nil 时接收全部事件.
+// 分发时会等待订阅者接收, 订阅者处理过慢会拖慢所有订阅者
+func (broker *Broker) Subscribe(match func(*ChangeEvent) bool) *Subscription {
+	ch := make(chan ChangeEvent, broker.buffer)
+	sub := &Subscription{C: ch, ch: ch, done: make(chan struct{}), match: match, broker: broker}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if broker.closed {
+		close(ch)
+		return sub
+	}
+	broker.subs[sub] = true
+	return sub
+}
+
+// Close 取消订阅, C 由分发协程在下一次分发或停止时关闭
+func (sub *Subscription) Close() {
+	broker := sub.broker
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if broker.subs[sub] {
+		delete(broker.subs, sub)
+		broker.closing = append(broker.closing, sub)
+		close(sub.done)
+	}
+}
+
+// Run 打开变更流并分发事件, 直到 ctx 取消或变更流出错, 返回时关闭所有订阅
+func (broker *Broker) Run(ctx context.Context) error {
+	defer broker.closeAll()
+	ctx, cancel := broker.query.client.streamContext(ctx)
+	defer cancel()
+	stream, err := broker.query.watchChanges(ctx, "Broker")
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+	for stream.Next(ctx) {
+		var event ChangeEvent
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+		broker.dispatch(ctx, &event)
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// dispatch 将事件发送给匹配的订阅者, 只在 Run 所在协程调用
+func (broker *Broker) dispatch(ctx context.Context, event *ChangeEvent) {
+	broker.mu.Lock()
+	subs := make([]*Subscription, 0, len(broker.subs))
+	for sub := range broker.subs {
+		subs = append(subs, sub)
+	}
+	closing := broker.closing
+	broker.closing = nil
+	broker.mu.Unlock()
+
+	for _, sub := range closing {
+		close(sub.ch)
+	}
+	for _, sub := range subs {
+		if sub.match != nil && !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- *event:
+		case <-sub.done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (broker *Broker) closeAll() {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	broker.closed = true
+	for sub := range broker.subs {
+		delete(broker.subs, sub)
+		close(sub.ch)
+	}
+	for _, sub := range broker.closing {
+		close(sub.ch)
+	}
+	broker.closing = nil
+}
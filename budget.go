@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// defaultTimeout 没有超时预算时单次操作的超时
+const defaultTimeout = 5 * time.Second
+
+type budgetKey struct{}
+
+// WithTimeoutBudget 让 ctx 上的操作按 ctx 剩余时间减去 margin 计算超时, 代替固定的超时;
+// ctx 没有截止时间时仍使用固定的超时
+func WithTimeoutBudget(ctx context.Context, margin time.Duration) context.Context {
+	return context.WithValue(ctx, budgetKey{}, margin)
+}
+
+// TimeoutBudget HTTP 中间件, 为请求设置总耗时 total(请求 ctx 已有更早的截止时间时保留原值),
+// 并让该请求内的数据库操作按剩余预算减去 margin 计算超时
+func TimeoutBudget(total, margin time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if total > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, total)
+				defer cancel()
+			}
+			next.ServeHTTP(w, r.WithContext(WithTimeoutBudget(ctx, margin)))
+		})
+	}
+}
+
+// operationTimeout 计算一次操作的超时, 没有预算时为 base; 有预算时为剩余预算, 并且不超过 limit(> 0 时).
+// 预算已经耗尽时返回 context.DeadlineExceeded
+func operationTimeout(ctx context.Context, base, limit time.Duration) (time.Duration, error) {
+	margin, ok := ctx.Value(budgetKey{}).(time.Duration)
+	if !ok {
+		return base, nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return base, nil
+	}
+	remaining := time.Until(deadline) - margin
+	if remaining <= 0 {
+		return 0, context.DeadlineExceeded
+	}
+	if limit > 0 && limit < remaining {
+		return limit, nil
+	}
+	return remaining, nil
+}
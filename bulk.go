@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrEmptyBulk Bulk 中没有任何操作
+var ErrEmptyBulk = errors.New("bulk has no operations")
+
+// Bulk 批量写入构造器, 把多种写操作合并成一次 BulkWrite 发送:
+//
+//	result, err := client.Collection("users").Bulk().
+//		InsertOne(user).
+//		UpdateOne(bson.M{"_id": id}, bson.M{"$inc": bson.M{"visits": 1}}).
+//		DeleteMany(bson.M{"expired": true}).
+//		Execute(ctx)
+//
+// 更新语句为带更新操作符的原始文档, 与 UpdateOneRaw 相同
+type Bulk struct {
+	query     *Query
+	models    []mongo.WriteModel
+	documents []interface{}
+	ordered   bool
+	counts    map[string]int
+	// err 添加操作时发现的错误(如修改分片键), Execute 时返回
+	err error
+}
+
+// Bulk 创建批量写入构造器, 默认有序执行
+func (query *Query) Bulk() *Bulk {
+	return &Bulk{query: query, ordered: true, counts: make(map[string]int)}
+}
+
+// Ordered 是否按顺序执行, 有序时遇到错误即停止; 无序时服务器可以并行执行并跳过失败的操作
+func (bulk *Bulk) Ordered(ordered bool) *Bulk {
+	bulk.ordered = ordered
+	return bulk
+}
+
+func (bulk *Bulk) add(kind string, model mongo.WriteModel, documents ...interface{}) *Bulk {
+	bulk.models = append(bulk.models, model)
+	bulk.documents = append(bulk.documents, documents...)
+	bulk.counts[kind]++
+	return bulk
+}
+
+// InsertOne 写入一条文档, 与 InsertOne 一样补充 _id
+func (bulk *Bulk) InsertOne(document interface{}) *Bulk {
+	data := BeforeCreate(document)
+	bulk.query.applyExpiry(data)
+	bulk.check(bulk.query.checkShardInsert(data))
+	return bulk.add("insert", mongo.NewInsertOneModel().SetDocument(data), data)
+}
+
+// UpdateOne 更新满足 filter 的第一条文档
+func (bulk *Bulk) UpdateOne(filter, update interface{}) *Bulk {
+	bulk.check(bulk.query.checkShardUpdate(filter, update))
+	model := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update)
+	if bulk.query.collation != nil {
+		model.SetCollation(bulk.query.collation)
+	}
+	return bulk.add("update", model, update)
+}
+
+// UpsertOne 更新满足 filter 的第一条文档, 不存在时插入
+func (bulk *Bulk) UpsertOne(filter, update interface{}) *Bulk {
+	bulk.check(bulk.query.checkShardUpdate(filter, update))
+	model := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true)
+	if bulk.query.collation != nil {
+		model.SetCollation(bulk.query.collation)
+	}
+	return bulk.add("update", model, update)
+}
+
+// UpdateMany 更新满足 filter 的所有文档
+func (bulk *Bulk) UpdateMany(filter, update interface{}) *Bulk {
+	bulk.check(bulk.query.checkShardUpdate(filter, update))
+	model := mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(update)
+	if bulk.query.collation != nil {
+		model.SetCollation(bulk.query.collation)
+	}
+	return bulk.add("update", model, update)
+}
+
+// ReplaceOne 替换满足 filter 的第一条文档
+func (bulk *Bulk) ReplaceOne(filter, replacement interface{}) *Bulk {
+	bulk.check(bulk.query.checkShardUpdate(filter, replacement))
+	model := mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(replacement)
+	if bulk.query.collation != nil {
+		model.SetCollation(bulk.query.collation)
+	}
+	return bulk.add("replace", model, replacement)
+}
+
+// DeleteOne 删除满足 filter 的第一条文档
+func (bulk *Bulk) DeleteOne(filter interface{}) *Bulk {
+	model := mongo.NewDeleteOneModel().SetFilter(filter)
+	if bulk.query.collation != nil {
+		model.SetCollation(bulk.query.collation)
+	}
+	return bulk.add("delete", model)
+}
+
+// DeleteMany 删除满足 filter 的所有文档
+func (bulk *Bulk) DeleteMany(filter interface{}) *Bulk {
+	model := mongo.NewDeleteManyModel().SetFilter(filter)
+	if bulk.query.collation != nil {
+		model.SetCollation(bulk.query.collation)
+	}
+	return bulk.add("delete", model)
+}
+
+// check 记录第一个错误
+func (bulk *Bulk) check(err error) {
+	if err != nil && bulk.err == nil {
+		bulk.err = err
+	}
+}
+
+// Len 已添加的操作数
+func (bulk *Bulk) Len() int {
+	return len(bulk.models)
+}
+
+// Execute 执行所有操作, 没有操作时返回 ErrEmptyBulk. 部分操作失败时返回 mongo.BulkWriteException,
+// 结果中仍包含已成功的数量
+func (bulk *Bulk) Execute(ctx context.Context) (result *mongo.BulkWriteResult, err error) {
+	if bulk.err != nil {
+		return nil, bulk.err
+	}
+	if len(bulk.models) == 0 {
+		return nil, ErrEmptyBulk
+	}
+	query := bulk.query
+	if err = query.checkSize(bulk.documents...); err != nil {
+		return
+	}
+	err = query.do(ctx, "Bulk", func(ctx context.Context) (err error) {
+		if span := spanFromContext(ctx); span != nil {
+			span.SetTag("db.bulk.ordered", bulk.ordered)
+			span.SetTag("db.bulk.operations", len(bulk.models))
+			for _, kind := range []string{"insert", "update", "replace", "delete"} {
+				span.SetTag("db.bulk."+kind+"s", bulk.counts[kind])
+			}
+		}
+		result, err = query.Table.BulkWrite(ctx, bulk.models, options.BulkWrite().SetOrdered(bulk.ordered).SetComment(commentValue(ctx)))
+		return
+	})
+	return
+}
@@ -0,0 +1,92 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCacheMiss 缓存不存在或已过期
+var ErrCacheMiss = errors.New("mongodb: cache miss")
+
+// Cache 基于 TTL 索引的键值缓存, 文档结构 {_id: key, value: ..., expire_at: ...}
+type Cache struct {
+	query   *Query
+	mu      sync.Mutex
+	indexed bool
+}
+
+// Cache 使用 collection 作为键值缓存
+func (client *MongoDBClient) Cache(collection string) *Cache {
+	return &Cache{query: client.Collection(collection)}
+}
+
+// ensureIndex 首次写入时创建 expire_at 上的 TTL 索引, 失败时下次写入重试
+func (cache *Cache) ensureIndex(ctx context.Context) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.indexed {
+		return nil
+	}
+	_, err := cache.query.createIndex(ctx, "CacheIndex", mongo.IndexModel{
+		Keys:    bson.D{{Key: "expire_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	cache.indexed = err == nil
+	return err
+}
+
+// Set 写入缓存, ttl <= 0 表示不过期
+func (cache *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if err := cache.ensureIndex(ctx); err != nil {
+		return err
+	}
+	var update bson.D
+	if ttl > 0 {
+		update = bson.D{{Key: "$set", Value: bson.D{{Key: "value", Value: value}, {Key: "expire_at", Value: time.Now().Add(ttl)}}}}
+	} else {
+		update = bson.D{
+			{Key: "$set", Value: bson.D{{Key: "value", Value: value}}},
+			{Key: "$unset", Value: bson.D{{Key: "expire_at", Value: ""}}},
+		}
+	}
+	return cache.query.do(ctx, "CacheSet", func(ctx context.Context) error {
+		_, err := cache.query.Table.UpdateOne(ctx, bson.D{{Key: "_id", Value: key}}, update, options.Update().SetUpsert(true).SetComment(commentValue(ctx)))
+		return err
+	})
+}
+
+// Get 读取缓存并解码到 v, 不存在或已过期返回 ErrCacheMiss
+func (cache *Cache) Get(ctx context.Context, key string, v interface{}) error {
+	var doc struct {
+		Value    bson.RawValue `bson:"value"`
+		ExpireAt *time.Time    `bson:"expire_at"`
+	}
+	err := cache.query.do(ctx, "CacheGet", func(ctx context.Context) error {
+		return cache.query.Table.FindOne(ctx, bson.D{{Key: "_id", Value: key}}, &options.FindOneOptions{Comment: commentOf(ctx)}).Decode(&doc)
+	})
+	if err == mongo.ErrNoDocuments {
+		return ErrCacheMiss
+	}
+	if err != nil {
+		return err
+	}
+	// TTL 索引后台每分钟清理一次, 读取时需要自己判断是否过期
+	if doc.ExpireAt != nil && !doc.ExpireAt.After(time.Now()) {
+		return ErrCacheMiss
+	}
+	return unmarshalValue(doc.Value, v)
+}
+
+// Delete 删除缓存
+func (cache *Cache) Delete(ctx context.Context, key string) error {
+	return cache.query.do(ctx, "CacheDelete", func(ctx context.Context) error {
+		_, err := cache.query.Table.DeleteOne(ctx, bson.D{{Key: "_id", Value: key}}, options.Delete().SetComment(commentValue(ctx)))
+		return err
+	})
+}
@@ -0,0 +1,103 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var errNoFullDocument = errors.New("change event has no full document")
+
+// ChangeEvent 变更流事件
+type ChangeEvent struct {
+	// ResumeToken 恢复令牌, 用于断点续传
+	ResumeToken   bson.Raw            `bson:"_id"`
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	Namespace     struct {
+		Database   string `bson:"db"`
+		Collection string `bson:"coll"`
+	} `bson:"ns"`
+	DocumentKey       bson.M   `bson:"documentKey"`
+	FullDocument      bson.Raw `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+// DecodeFullDocument 解码完整文档, delete 事件没有完整文档
+func (event *ChangeEvent) DecodeFullDocument(v interface{}) error {
+	if len(event.FullDocument) == 0 {
+		return errNoFullDocument
+	}
+	return unmarshal(event.FullDocument, v)
+}
+
+// changePipeline 将链式条件转换成变更流的 $match, 条件作用于事件字段, 如 operationType、fullDocument.status
+func (query *Query) changePipeline() []interface{} {
+	if len(query.filter) == 0 {
+		return []interface{}{}
+	}
+	return []interface{}{bson.D{{Key: "$match", Value: query.filter}}}
+}
+
+// follow 监听集合变更并回调, 变更流出错时先调用 reload 重新加载全量数据再重新监听, 直到 ctx 取消
+func (query *Query) follow(ctx context.Context, method string, reload func(ctx context.Context) error, onEvent func(*ChangeEvent)) {
+	ctx, cancel := query.client.streamContext(ctx)
+	defer cancel()
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := query.followOnce(ctx, method, reload, onEvent, &backoff)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil && Log != nil {
+			Log.Warn("MongoDB变更流中断->", query.namespace(), " ", err, labelSuffix(query.client.Labels()))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// followOnce 打开一次变更流并处理事件直到出错, 成功打开后重置 backoff
+func (query *Query) followOnce(ctx context.Context, method string, reload func(ctx context.Context) error, onEvent func(*ChangeEvent), backoff *time.Duration) error {
+	stream, err := query.watchChanges(ctx, method)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+	// 流建立后再全量加载, 避免加载与监听之间的变更丢失
+	if err := reload(ctx); err != nil {
+		return err
+	}
+	*backoff = time.Second
+	for stream.Next(ctx) {
+		var event ChangeEvent
+		if err := stream.Decode(&event); err != nil {
+			return err
+		}
+		onEvent(&event)
+	}
+	return stream.Err()
+}
+
+// watchChanges 打开链式条件对应的变更流. 只有打开计入操作统计, 接收事件的过程持续整个订阅期间, 不作为一次操作记录
+func (query *Query) watchChanges(ctx context.Context, method string) (stream *mongo.ChangeStream, err error) {
+	err = query.run(ctx, method, func(ctx context.Context) (err error) {
+		stream, err = query.Table.Watch(ctx, query.changePipeline(), options.ChangeStream().SetFullDocument(options.UpdateLookup))
+		return
+	})
+	return
+}
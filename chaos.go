@@ -0,0 +1,82 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrChaos 故障注入产生的错误
+var ErrChaos = errors.New("mongodb: injected fault")
+
+// ChaosRule 故障注入规则, 只应在测试环境使用, 用于验证超时、重试、熔断是否按预期工作
+type ChaosRule struct {
+	// Collection 生效的集合名, 为空对所有集合生效
+	Collection string
+	// Method 生效的方法名(FindOne、UpdateOne 等), 为空对所有方法生效
+	Method string
+	// Latency 执行前增加的延迟, 超过操作超时时操作返回 context.DeadlineExceeded
+	Latency time.Duration
+	// Jitter 在 Latency 基础上再随机增加 [0, Jitter) 的延迟
+	Jitter time.Duration
+	// ErrorRate 返回错误而不执行操作的概率, 0 到 1
+	ErrorRate float64
+	// Err 注入的错误, 默认 ErrChaos
+	Err error
+}
+
+// SetChaos 替换连接的故障注入规则, 对之后的操作生效; 多条规则匹配时依次叠加延迟, 任意一条注入错误即返回
+func (client *MongoDBClient) SetChaos(rules ...ChaosRule) {
+	client.chaos.Store(append([]ChaosRule(nil), rules...))
+}
+
+// ClearChaos 关闭故障注入
+func (client *MongoDBClient) ClearChaos() {
+	client.chaos.Store([]ChaosRule(nil))
+}
+
+// chaosRules 当前的故障注入规则, 没有调用过 SetChaos 时使用 Opt.Chaos
+func (client *MongoDBClient) chaosRules() []ChaosRule {
+	if rules, ok := client.chaos.Load().([]ChaosRule); ok {
+		return rules
+	}
+	if client.opt != nil {
+		return client.opt.Chaos
+	}
+	return nil
+}
+
+// injectFault 按规则注入延迟和错误, 延迟期间 ctx 结束时返回 ctx 的错误
+func (query *Query) injectFault(ctx context.Context, method string) error {
+	rules := query.client.chaosRules()
+	if len(rules) == 0 {
+		return nil
+	}
+	table := query.Table.Name()
+	for _, rule := range rules {
+		if (rule.Collection != "" && rule.Collection != table) || (rule.Method != "" && rule.Method != method) {
+			continue
+		}
+		delay := rule.Latency
+		if rule.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(rule.Jitter)))
+		}
+		if delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if rule.ErrorRate > 0 && rand.Float64() < rule.ErrorRate {
+			if rule.Err != nil {
+				return rule.Err
+			}
+			return ErrChaos
+		}
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// DefaultChunkSize InsertManyChunked 默认每批文档数
+	DefaultChunkSize = 1000
+	// maxChunkSize 单批最多文档数(maxWriteBatchSize)
+	maxChunkSize = 100000
+	// maxChunkBytes 单批最大字节数, 为 16MB 消息上限留出命令本身的空间
+	maxChunkBytes = 16*1024*1024 - 16*1024
+)
+
+// ChunkError 某一批写入失败
+type ChunkError struct {
+	// Chunk 批次序号, 从 0 开始
+	Chunk int
+	// Offset 该批第一个文档在输入中的下标
+	Offset int
+	Count  int
+	Err    error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (documents %d-%d): %v", e.Chunk, e.Offset, e.Offset+e.Count-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// ChunkedInsertResult 分批写入的汇总结果
+type ChunkedInsertResult struct {
+	InsertedIDs []interface{}
+	Chunks      int
+	Errors      []*ChunkError
+}
+
+// Err 有批次失败时返回第一个失败批次的错误
+func (result *ChunkedInsertResult) Err() error {
+	if len(result.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d chunks failed, first: %w", len(result.Errors), result.Chunks, result.Errors[0])
+}
+
+// InsertManyChunked 将大量文档按 chunkSize 及 16MB 限制拆成多批写入, 每批单独计算超时,
+// 某一批失败不影响后续批次, 失败信息记录在结果的 Errors 中
+func (query *Query) InsertManyChunked(ctx context.Context, documents interface{}, chunkSize int) (*ChunkedInsertResult, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize > maxChunkSize {
+		chunkSize = maxChunkSize
+	}
+	generated := generatedIDs(documents)
+	data, ok := BeforeCreate(documents).([]interface{})
+	if !ok {
+		return nil, errors.New("documents must be a slice")
+	}
+	query.applyExpiry(data...)
+	if err := query.checkSize(data...); err != nil {
+		return nil, err
+	}
+	if err := query.checkShardInsert(data...); err != nil {
+		return nil, err
+	}
+	result := &ChunkedInsertResult{}
+	offset := 0
+	for offset < len(data) {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		end, err := chunkEnd(data, offset, chunkSize)
+		if err != nil {
+			return result, err
+		}
+		chunk := result.Chunks
+		result.Chunks++
+		var inserted *mongo.InsertManyResult
+		err = query.do(ctx, "InsertManyChunked", func(ctx context.Context) (err error) {
+			inserted, err = query.insertMany(ctx, data[offset:end], chunkGenerated(generated, offset, end))
+			return
+		})
+		if inserted != nil {
+			result.InsertedIDs = append(result.InsertedIDs, inserted.InsertedIDs...)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, &ChunkError{Chunk: chunk, Offset: offset, Count: end - offset, Err: err})
+		}
+		offset = end
+	}
+	return result, result.Err()
+}
+
+// chunkGenerated 一批文档对应的 _id 生成标记
+func chunkGenerated(generated []bool, offset, end int) []bool {
+	if end > len(generated) {
+		return nil
+	}
+	return generated[offset:end]
+}
+
+// chunkEnd 计算从 offset 开始的一批文档的结束下标
+func chunkEnd(data []interface{}, offset, chunkSize int) (int, error) {
+	size := 0
+	end := offset
+	for end < len(data) && end-offset < chunkSize {
+		raw, err := marshal(data[end])
+		if err != nil {
+			return end, fmt.Errorf("document %d: %w", end, err)
+		}
+		if size+len(raw) > maxChunkBytes && end > offset {
+			break
+		}
+		size += len(raw)
+		end++
+	}
+	return end, nil
+}
+
+// DeleteByIDs 按 _id 删除文档, ids 为单个或切片形式的 ObjectID、十六进制字符串或 UUID, 十六进制字符串自动转换为 ObjectID.
+// 链式条件同样生效; ID 很多时按 DefaultChunkSize 及 16MB 限制拆成多批 $in 删除, 某一批失败时返回 ChunkError
+// 和已删除的文档数, 之前的批次不会回滚
+func (query *Query) DeleteByIDs(ctx context.Context, ids interface{}) (deleted int64, err error) {
+	if ids == nil {
+		return 0, nil
+	}
+	data := normalizeIDs(ids)
+	chunk, offset := 0, 0
+	for offset < len(data) {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		end, err := idChunkEnd(data, offset, DefaultChunkSize)
+		if err != nil {
+			return deleted, err
+		}
+		scoped := query.and(bson.E{Key: "_id", Value: bson.D{{Key: "$in", Value: data[offset:end]}}})
+		err = scoped.do(ctx, "DeleteByIDs", func(ctx context.Context) error {
+			result, err := scoped.Table.DeleteMany(ctx, scoped.filter, &options.DeleteOptions{Collation: scoped.collation, Comment: commentValue(ctx)})
+			if err != nil {
+				return err
+			}
+			deleted += result.DeletedCount
+			return nil
+		})
+		if err != nil {
+			return deleted, &ChunkError{Chunk: chunk, Offset: offset, Count: end - offset, Err: err}
+		}
+		chunk++
+		offset = end
+	}
+	return
+}
+
+// idChunkEnd 计算从 offset 开始的一批 ID 的结束下标, 按数组元素编码后的大小估算
+func idChunkEnd(ids []interface{}, offset, chunkSize int) (int, error) {
+	size := 0
+	end := offset
+	for end < len(ids) && end-offset < chunkSize {
+		raw, err := marshalValue(ids[end])
+		if err != nil {
+			return end, fmt.Errorf("id %d: %w", end, err)
+		}
+		// 类型 1 字节, 下标作为键最多 7 字节加结束符
+		n := len(raw.Value) + 8
+		if size+n > maxChunkBytes && end > offset {
+			break
+		}
+		size += n
+		end++
+	}
+	return end, nil
+}
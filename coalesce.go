@@ -0,0 +1,191 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCoalescerClosed 合并器已关闭
+var ErrCoalescerClosed = errors.New("mongodb: update coalescer is closed")
+
+// CoalesceOptions 更新合并参数
+type CoalesceOptions struct {
+	// Window 第一条更新到达后等待的时间, 窗口内的更新合并成一次 BulkWrite, 默认 10ms
+	Window time.Duration
+	// MaxBatch 单次 BulkWrite 最多的更新数, 达到时立即写入, 默认 1000
+	MaxBatch int
+}
+
+// Coalescer 将短时间内的大量 UpdateOne 合并成一次无序 BulkWrite, 适合高频计数器等场景
+type Coalescer struct {
+	query    *Query
+	window   time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending []*UpdateFuture
+	timer   *time.Timer
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// UpdateFuture 一次合并更新的结果, 批量写入完成后可用
+type UpdateFuture struct {
+	model  mongo.WriteModel
+	done   chan struct{}
+	result *UpdateResult
+	err    error
+}
+
+// Coalescer 创建集合上的更新合并器, 用完需要 Close
+func (query *Query) Coalescer(opts *CoalesceOptions) *Coalescer {
+	coalescer := &Coalescer{query: query, window: 10 * time.Millisecond, maxBatch: 1000}
+	if opts != nil {
+		if opts.Window > 0 {
+			coalescer.window = opts.Window
+		}
+		if opts.MaxBatch > 0 {
+			coalescer.maxBatch = opts.MaxBatch
+		}
+	}
+	return coalescer
+}
+
+// UpdateOne 提交一条原始更新语句, 在下一次批量写入时执行
+func (coalescer *Coalescer) UpdateOne(filter, update interface{}) *UpdateFuture {
+	return coalescer.add(mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update))
+}
+
+// UpsertOne 提交一条 upsert 更新语句
+func (coalescer *Coalescer) UpsertOne(filter, update interface{}) *UpdateFuture {
+	return coalescer.add(mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+}
+
+func (coalescer *Coalescer) add(model mongo.WriteModel) *UpdateFuture {
+	future := &UpdateFuture{model: model, done: make(chan struct{})}
+	coalescer.mu.Lock()
+	defer coalescer.mu.Unlock()
+	if coalescer.closed {
+		future.resolve(nil, ErrCoalescerClosed)
+		return future
+	}
+	coalescer.pending = append(coalescer.pending, future)
+	if len(coalescer.pending) >= coalescer.maxBatch {
+		coalescer.flushLocked()
+	} else if coalescer.timer == nil {
+		coalescer.timer = time.AfterFunc(coalescer.window, coalescer.flush)
+	}
+	return future
+}
+
+// flush 窗口到期时写入
+func (coalescer *Coalescer) flush() {
+	coalescer.mu.Lock()
+	defer coalescer.mu.Unlock()
+	coalescer.flushLocked()
+}
+
+// flushLocked 取出当前批次在后台写入, 调用时需持有锁
+func (coalescer *Coalescer) flushLocked() {
+	if coalescer.timer != nil {
+		coalescer.timer.Stop()
+		coalescer.timer = nil
+	}
+	if len(coalescer.pending) == 0 {
+		return
+	}
+	batch := coalescer.pending
+	coalescer.pending = nil
+	coalescer.wg.Add(1)
+	go func() {
+		defer coalescer.wg.Done()
+		coalescer.write(batch)
+	}()
+}
+
+// write 执行一次 BulkWrite 并按下标把结果分发给每个 future
+func (coalescer *Coalescer) write(batch []*UpdateFuture) {
+	models := make([]mongo.WriteModel, len(batch))
+	for i, future := range batch {
+		models[i] = future.model
+	}
+	query := coalescer.query
+	var result *mongo.BulkWriteResult
+	err := query.do(context.Background(), "CoalescedUpdate", func(ctx context.Context) (err error) {
+		result, err = query.Table.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false).SetComment(commentValue(ctx)))
+		return
+	})
+	var bulkErr mongo.BulkWriteException
+	writeErrors := map[int]error{}
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			writeErrors[writeErr.Index] = writeErr
+		}
+		err = nil
+	}
+	for i, future := range batch {
+		if err != nil {
+			future.resolve(nil, err)
+			continue
+		}
+		if writeErr, ok := writeErrors[i]; ok {
+			future.resolve(nil, writeErr)
+			continue
+		}
+		updateResult := &UpdateResult{}
+		if result != nil {
+			if id, ok := result.UpsertedIDs[int64(i)]; ok {
+				updateResult.UpsertedCount = 1
+				updateResult.UpsertedID = idString(id)
+				updateResult.rawUpsertedID = id
+			}
+		}
+		future.resolve(updateResult, nil)
+	}
+}
+
+// Close 立即写入剩余的更新并等待全部批次完成, 之后提交的更新返回 ErrCoalescerClosed
+func (coalescer *Coalescer) Close(ctx context.Context) error {
+	coalescer.mu.Lock()
+	coalescer.closed = true
+	coalescer.flushLocked()
+	coalescer.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		coalescer.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (future *UpdateFuture) resolve(result *UpdateResult, err error) {
+	future.result = result
+	future.err = err
+	close(future.done)
+}
+
+// Done 批量写入完成时关闭
+func (future *UpdateFuture) Done() <-chan struct{} {
+	return future.done
+}
+
+// Wait 等待批量写入完成. 批量写入不返回单条更新的匹配数,
+// 结果中只有 upsert 新建的 _id
+func (future *UpdateFuture) Wait(ctx context.Context) (*UpdateResult, error) {
+	select {
+	case <-future.done:
+		return future.result, future.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"bytes"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+)
+
+// registry 本包使用的 BSON 编解码注册表, 设置在每个连接上, 包内所有编解码都通过它进行:
+// 没有 bson 标签的结构体字段按 FieldNaming 命名, 枚举类型使用 RegisterEnum 注册的编解码器
+var registry = newRegistry()
+
+func newRegistry() *bsoncodec.Registry {
+	codec, err := bsoncodec.NewStructCodec(bsoncodec.StructTagParserFunc(namingTagParser))
+	if err != nil {
+		panic(err)
+	}
+	reg := bson.NewRegistry()
+	reg.RegisterKindEncoder(reflect.Struct, codec)
+	reg.RegisterKindDecoder(reflect.Struct, codec)
+	return reg
+}
+
+// Registry 本包使用的 BSON 编解码注册表, 在包外自行编解码时使用, 与写入数据库时的字段名保持一致
+func Registry() *bsoncodec.Registry {
+	return registry
+}
+
+// marshal 按 registry 编码文档
+func marshal(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	vw, err := bsonrw.NewBSONValueWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := bson.NewEncoder(vw)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.SetRegistry(registry); err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unmarshal 按 registry 解码文档
+func unmarshal(data []byte, v interface{}) error {
+	dec, err := bson.NewDecoder(bsonrw.NewBSONDocumentReader(data))
+	if err != nil {
+		return err
+	}
+	if err := dec.SetRegistry(registry); err != nil {
+		return err
+	}
+	return dec.Decode(v)
+}
+
+// unmarshalValue 按 registry 解码单个值
+func unmarshalValue(value bson.RawValue, v interface{}) error {
+	return value.UnmarshalWithRegistry(registry, v)
+}
+
+// marshalValue 按 registry 编码单个值
+func marshalValue(v interface{}) (bson.RawValue, error) {
+	data, err := marshal(bson.D{{Key: "v", Value: v}})
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return bson.Raw(data).LookupErr("v")
+}
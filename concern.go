@@ -0,0 +1,37 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// ReadPref 指定该查询的读偏好, 覆盖 Opt.ReadPreference, 如统计类查询使用 readpref.SecondaryPreferred()
+func (query *Query) ReadPref(rp *readpref.ReadPref) *Query {
+	return query.withTableOptions(options.Collection().SetReadPreference(rp))
+}
+
+// ReadConcern 指定该查询的读关注, 覆盖 Opt.ReadConcern
+func (query *Query) ReadConcern(rc *readconcern.ReadConcern) *Query {
+	return query.withTableOptions(options.Collection().SetReadConcern(rc))
+}
+
+// WriteConcern 指定该查询的写关注, 覆盖 Opt.WriteConcern, 如 writeconcern.New(writeconcern.WMajority())
+func (query *Query) WriteConcern(wc *writeconcern.WriteConcern) *Query {
+	return query.withTableOptions(options.Collection().SetWriteConcern(wc))
+}
+
+// withTableOptions 在带 opts 的集合副本上执行之后的操作, 不影响共享的集合句柄
+func (query *Query) withTableOptions(opts *options.CollectionOptions) *Query {
+	query = query.clone()
+	table, err := query.Table.Clone(opts)
+	if err != nil {
+		if Log != nil {
+			Log.Warn("MongoDB集合选项设置失败->", query.namespace(), " ", err, labelSuffix(query.client.Labels()))
+		}
+		return query
+	}
+	query.Table = table
+	return query
+}
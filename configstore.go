@@ -0,0 +1,125 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConfigStore 应用配置存储, 每个配置是一个文档, _id 为配置名.
+// Start 监听变更流期间, 读取结果按配置名和调用方角色(WithRole)缓存在内存中, 通过变更流失效;
+// 未调用 Start 时不缓存, 每次读取都查询数据库. 变更流中断到重建之间仍可能读到中断前缓存的旧值
+type ConfigStore struct {
+	query *Query
+	mu    sync.RWMutex
+	// docs 配置名 -> 角色 -> 文档, 配置了 ReadPolicy 时不同角色读到的字段可能不同
+	docs map[string]map[string]bson.Raw
+	// generations 配置名 -> 失效次数, epoch 缓存清空次数, 查询期间发生失效时不写入缓存
+	generations map[string]uint64
+	epoch       uint64
+	watching    bool
+	listeners   []func(key string)
+}
+
+// ConfigStore 使用 collection 存储应用配置
+func (client *MongoDBClient) ConfigStore(collection string) *ConfigStore {
+	return &ConfigStore{
+		query:       client.Collection(collection),
+		docs:        make(map[string]map[string]bson.Raw),
+		generations: make(map[string]uint64),
+	}
+}
+
+// Start 在后台监听配置变更, 变更的配置从缓存中移除并通知 OnChange 注册的回调, 直到 ctx 取消.
+// 变更流建立后才开始缓存, 监听结束后清空缓存并停止缓存
+func (store *ConfigStore) Start(ctx context.Context) {
+	go func() {
+		store.query.follow(ctx, "ConfigWatch", store.reset, store.invalidate)
+		store.mu.Lock()
+		store.watching = false
+		store.docs = make(map[string]map[string]bson.Raw)
+		store.epoch++
+		store.mu.Unlock()
+	}()
+}
+
+// OnChange 注册配置变更回调, 回调在监听协程中执行
+func (store *ConfigStore) OnChange(fn func(key string)) {
+	store.mu.Lock()
+	store.listeners = append(store.listeners, fn)
+	store.mu.Unlock()
+}
+
+// reset 变更流建立或重建时清空缓存, 中断期间的变更无法得知
+func (store *ConfigStore) reset(ctx context.Context) error {
+	store.mu.Lock()
+	store.docs = make(map[string]map[string]bson.Raw)
+	store.epoch++
+	store.watching = true
+	store.mu.Unlock()
+	return nil
+}
+
+// invalidate 使变更的配置缓存失效
+func (store *ConfigStore) invalidate(event *ChangeEvent) {
+	key := fmt.Sprint(event.DocumentKey["_id"])
+	store.mu.Lock()
+	delete(store.docs, key)
+	store.generations[key]++
+	listeners := store.listeners
+	store.mu.Unlock()
+	for _, fn := range listeners {
+		fn(key)
+	}
+}
+
+// Unmarshal 读取配置 key 并解码到 v, 优先使用缓存, 不存在时返回 mongo.ErrNoDocuments
+func (store *ConfigStore) Unmarshal(ctx context.Context, key string, v interface{}) error {
+	role := RoleFromContext(ctx)
+	store.mu.RLock()
+	raw, ok := store.docs[key][role]
+	generation, epoch := store.generations[key], store.epoch
+	store.mu.RUnlock()
+	if !ok {
+		raws, err := store.query.Where(bson.D{{Key: "_id", Value: key}}).Limit(1).FindRaw(ctx)
+		if err != nil {
+			return err
+		}
+		if len(raws) == 0 {
+			return mongo.ErrNoDocuments
+		}
+		raw = raws[0]
+		store.mu.Lock()
+		// 查询期间配置被修改或缓存被清空时, 读到的可能是旧值, 不写入缓存
+		if store.watching && store.generations[key] == generation && store.epoch == epoch {
+			if store.docs[key] == nil {
+				store.docs[key] = make(map[string]bson.Raw)
+			}
+			store.docs[key][role] = raw
+		}
+		store.mu.Unlock()
+	}
+	return unmarshal(raw, v)
+}
+
+// Set 写入配置, 其他实例通过变更流感知
+func (store *ConfigStore) Set(ctx context.Context, key string, value interface{}) error {
+	fields, err := toDocument(value)
+	if err != nil {
+		return err
+	}
+	// toDocument 对 bson.M 直接返回原值, 复制后再设置 _id, 不修改调用方的 map
+	doc := make(bson.M, len(fields)+1)
+	for k, v := range fields {
+		doc[k] = v
+	}
+	doc["_id"] = key
+	return store.query.do(ctx, "ConfigSet", func(ctx context.Context) error {
+		_, err := store.query.Table.ReplaceOne(ctx, bson.D{{Key: "_id", Value: key}}, doc, options.Replace().SetUpsert(true).SetComment(commentValue(ctx)))
+		return err
+	})
+}
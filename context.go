@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type clientKey struct{}
+
+// WithClient 将连接放入 context, 深层调用通过 FromContext 取出, 不需要逐层传参
+func WithClient(ctx context.Context, client *MongoDBClient) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// FromContext 取出 WithClient 放入的连接, 没有时返回 nil
+func FromContext(ctx context.Context) *MongoDBClient {
+	client, _ := ctx.Value(clientKey{}).(*MongoDBClient)
+	return client
+}
+
+// WithSession 将会话放入 context, 使用该 context 的操作都在此会话中执行
+func WithSession(ctx context.Context, session mongo.Session) context.Context {
+	return mongo.NewSessionContext(ctx, session)
+}
+
+// SessionFromContext 取出 context 中的会话, 没有时返回 nil
+func SessionFromContext(ctx context.Context) mongo.Session {
+	return mongo.SessionFromContext(ctx)
+}
+
+// WithContext 指定不带 context 参数的方法(FindOne、UpdateOne 等)使用的 context,
+// context 中的会话、截止时间都会生效
+func (query *Query) WithContext(ctx context.Context) *Query {
+	query = query.clone()
+	query.ctx = ctx
+	return query
+}
+
+// baseContext 不带 context 参数的方法使用的 context
+func (query *Query) baseContext() context.Context {
+	if query.ctx != nil {
+		return query.ctx
+	}
+	return context.Background()
+}
+
+// WithSession 返回在 session 中执行所有操作的查询对象, 配合因果一致性会话实现写后读
+func (query *Query) WithSession(session mongo.Session) *Query {
+	query = query.clone()
+	query.session = session
+	return query
+}
+
+// SessionClient 绑定了会话的连接, 从它得到的所有集合操作都在该会话中执行
+type SessionClient struct {
+	*MongoDBClient
+	Session mongo.Session
+}
+
+// WithSession 返回绑定 session 的连接, 用于跨多个集合的因果链:
+//
+//	session, _ := client.Client.StartSession(options.Session().SetCausalConsistency(true))
+//	defer session.EndSession(ctx)
+//	db := client.WithSession(session)
+//	db.Collection("orders").InsertOne(order)
+//	db.Collection("order_views").FindOne(&view)
+func (client *MongoDBClient) WithSession(session mongo.Session) *SessionClient {
+	return &SessionClient{MongoDBClient: client, Session: session}
+}
+
+// Collection 得到在会话中执行的mongo操作对象
+func (client *SessionClient) Collection(table string) *Query {
+	return client.MongoDBClient.Collection(table).WithSession(client.Session)
+}
+
+// sessionContext 查询绑定了会话时把会话放入 ctx
+func (query *Query) sessionContext(ctx context.Context) context.Context {
+	if query.session == nil {
+		return ctx
+	}
+	return mongo.NewSessionContext(ctx, query.session)
+}
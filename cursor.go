@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Cursor 流式读取结果的游标, 用完需要 Close
+type Cursor struct {
+	query  *Query
+	method string
+	cursor *mongo.Cursor
+}
+
+// Next 移动到下一条文档, 没有更多文档或出错时返回 false
+func (cursor *Cursor) Next(ctx context.Context) bool {
+	return cursor.cursor.Next(ctx)
+}
+
+// Decode 解码当前文档
+func (cursor *Cursor) Decode(v interface{}) error {
+	return cursor.query.decodeOutside(cursor.method, cursor.cursor.Current, v)
+}
+
+// Current 当前文档的原始 BSON, 下一次 Next 后失效
+func (cursor *Cursor) Current() bson.Raw {
+	return cursor.cursor.Current
+}
+
+// Err 迭代过程中的错误
+func (cursor *Cursor) Err() error {
+	return cursor.cursor.Err()
+}
+
+// Close 关闭游标
+func (cursor *Cursor) Close(ctx context.Context) error {
+	return cursor.cursor.Close(ctx)
+}
+
+// each 逐条回调, 回调返回错误或 context 取消时停止
+func (cursor *Cursor) each(ctx context.Context, fn func() error) error {
+	defer cursor.Close(context.Background())
+	for cursor.Next(ctx) {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// AggregateCursor 执行聚合并返回游标, 结果不会一次性加载到内存, 不附加操作超时
+func (query *Query) AggregateCursor(ctx context.Context, pipeline interface{}) (*Cursor, error) {
+	var cursor *mongo.Cursor
+	err := query.run(ctx, "AggregateCursor", func(ctx context.Context) (err error) {
+		pipeline, err := query.readPipeline(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		cursor, err = query.Table.Aggregate(ctx, pipeline, query.aggregateOptions(ctx))
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{query: query, method: "AggregateCursor", cursor: cursor}, nil
+}
+
+// AggregateEach 执行聚合并逐条回调, handler 返回错误时停止
+func (query *Query) AggregateEach(ctx context.Context, pipeline interface{}, handler func(bson.M) error) error {
+	cursor, err := query.AggregateCursor(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.each(ctx, func() error {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		return handler(doc)
+	})
+}
+
+// find 按链式条件打开查询游标
+func (query *Query) find(ctx context.Context, method string) (*Cursor, error) {
+	var cursor *mongo.Cursor
+	err := query.run(ctx, method, func(ctx context.Context) (err error) {
+		cursor, err = query.Table.Find(ctx, query.filter, query.findOptions(ctx))
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Cursor{query: query, method: method, cursor: cursor}, nil
+}
+
+// ForEach 流式遍历链式查询的结果, 每条文档解码到 prototype 类型的新实例, 以指针传给 fn,
+// fn 返回错误或 context 取消时停止
+func (query *Query) ForEach(ctx context.Context, prototype interface{}, fn func(item interface{}) error) error {
+	typ := reflect.TypeOf(prototype)
+	if typ == nil {
+		return errors.New("prototype must not be nil")
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	cursor, err := query.find(ctx, "ForEach")
+	if err != nil {
+		return err
+	}
+	return cursor.each(ctx, func() error {
+		item := reflect.New(typ).Interface()
+		if err := cursor.Decode(item); err != nil {
+			return err
+		}
+		return fn(item)
+	})
+}
+
+// FindCursor 按链式条件打开查询游标, 逐条读取结果而不是一次性加载到内存, 不附加操作超时, 用完需要 Close:
+//
+//	cursor, err := client.Collection("events").Where(filter).FindCursor(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	defer cursor.Close(ctx)
+//	for cursor.Next(ctx) {
+//		var event Event
+//		if err := cursor.Decode(&event); err != nil {
+//			return err
+//		}
+//	}
+//	return cursor.Err()
+func (query *Query) FindCursor(ctx context.Context) (*Cursor, error) {
+	return query.find(ctx, "FindCursor")
+}
+
+// FindEach 按链式条件流式遍历结果, 每条文档调用一次 fn, 在 fn 中通过 cursor.Decode 解码;
+// fn 返回错误或 context 取消时停止, 游标自动关闭
+func (query *Query) FindEach(ctx context.Context, fn func(cursor *Cursor) error) error {
+	cursor, err := query.find(ctx, "FindEach")
+	if err != nil {
+		return err
+	}
+	return cursor.each(ctx, func() error {
+		return fn(cursor)
+	})
+}
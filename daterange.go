@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TimeUnit 时间分桶的粒度
+type TimeUnit string
+
+const (
+	Day   TimeUnit = "day"
+	Week  TimeUnit = "week"
+	Month TimeUnit = "month"
+)
+
+// TimeBucket 时间分桶统计
+type TimeBucket struct {
+	Start time.Time `bson:"_id"`
+	Count int64     `bson:"count"`
+}
+
+// WhereDateBetween 追加时间范围条件 from <= field < to
+func (query *Query) WhereDateBetween(field string, from, to time.Time) *Query {
+	return query.and(bson.E{Key: field, Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}})
+}
+
+// WhereToday 追加条件: field 在本地时区的今天
+func (query *Query) WhereToday(field string) *Query {
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return query.WhereDateBetween(field, start, start.AddDate(0, 0, 1))
+}
+
+// WhereLastNDays 追加条件: field 在最近 n 天内(从 n 天前的此刻到现在)
+func (query *Query) WhereLastNDays(field string, n int) *Query {
+	now := time.Now()
+	return query.and(bson.E{Key: field, Value: bson.D{{Key: "$gte", Value: now.AddDate(0, 0, -n)}}})
+}
+
+// BucketByTime 按时间粒度分组计数的 $group 阶段, 需要 MongoDB 5.0+($dateTrunc),
+// timezone 为空时使用 UTC, 输出 {_id: 桶起始时间, count: 数量}
+func BucketByTime(field string, unit TimeUnit, timezone string) bson.D {
+	trunc := bson.D{{Key: "date", Value: "$" + field}, {Key: "unit", Value: string(unit)}}
+	if timezone != "" {
+		trunc = append(trunc, bson.E{Key: "timezone", Value: timezone})
+	}
+	return bson.D{{Key: "$group", Value: bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$dateTrunc", Value: trunc}}},
+		{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+	}}}
+}
+
+// CountByTime 按时间粒度统计满足条件的文档数量, 按时间升序
+func (query *Query) CountByTime(ctx context.Context, field string, unit TimeUnit, timezone string) (buckets []TimeBucket, err error) {
+	err = query.do(ctx, "CountByTime", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, bson.A{
+			bson.D{{Key: "$match", Value: query.filter}},
+			BucketByTime(field, unit, timezone),
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		})
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{Collation: query.collation, Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		buckets = make([]TimeBucket, 0)
+		return cursor.All(ctx, &buckets)
+	})
+	return
+}
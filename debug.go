@@ -0,0 +1,101 @@
+package mongodb
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// recentSlowQueries 保留的最近慢查询条数
+const recentSlowQueries = 100
+
+// slowLog 最近的慢查询
+type slowLog struct {
+	mu      sync.Mutex
+	queries []SlowQuery
+}
+
+func (log *slowLog) add(slow SlowQuery) {
+	if log == nil {
+		return
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.queries = append(log.queries, slow)
+	if len(log.queries) > recentSlowQueries {
+		log.queries = log.queries[len(log.queries)-recentSlowQueries:]
+	}
+}
+
+func (log *slowLog) recent() []SlowQuery {
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	queries := make([]SlowQuery, len(log.queries))
+	copy(queries, log.queries)
+	return queries
+}
+
+type debugConnection struct {
+	Name        string            `json:"name"`
+	Database    string            `json:"database"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Healthy     bool              `json:"healthy"`
+	Error       string            `json:"error,omitempty"`
+	Maintenance bool              `json:"maintenance"`
+	Pool        PoolStats         `json:"pool"`
+}
+
+type debugReport struct {
+	Connections []debugConnection `json:"connections"`
+	Operations  []OpStats         `json:"operations"`
+	SlowQueries []SlowQuery       `json:"slow_queries"`
+}
+
+// DebugHandler 调试接口, 以 JSON 输出已建立的连接、连接池、健康状态、操作统计和最近的慢查询,
+// 可以挂载到 /debug/mongodb, 注意不要暴露到公网
+func (configs *Configs) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		configs.mu.RLock()
+		names := make([]string, 0, len(configs.connections))
+		for name := range configs.connections {
+			names = append(names, name)
+		}
+		clients := make(map[string]*MongoDBClient, len(names))
+		for _, name := range names {
+			clients[name] = configs.connections[name]
+		}
+		configs.mu.RUnlock()
+		sort.Strings(names)
+
+		report := debugReport{
+			Connections: make([]debugConnection, 0, len(names)),
+			Operations:  configs.Stats(),
+			SlowQueries: configs.slowLog.recent(),
+		}
+		for _, name := range names {
+			client := clients[name]
+			ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+			err := client.Client.Ping(ctx, nil)
+			cancel()
+			conn := debugConnection{
+				Name:        name,
+				Database:    client.database(),
+				Labels:      client.Labels(),
+				Healthy:     err == nil,
+				Maintenance: client.InMaintenance(),
+				Pool:        client.PoolStats(),
+			}
+			if err != nil {
+				conn.Error = err.Error()
+			}
+			report.Connections = append(report.Connections, conn)
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	})
+}
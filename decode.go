@@ -0,0 +1,95 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DecodeHook 自定义解码, 可以按原始 BSON 处理异构文档或自定义反序列化
+type DecodeHook func(raw bson.Raw, v interface{}) error
+
+// SetDecodeHook 设置集合的解码钩子, 该集合上所有查询结果都通过 hook 解码, nil 恢复默认解码
+func (collection *Collection) SetDecodeHook(hook DecodeHook) {
+	collection.mu.Lock()
+	collection.decodeHook = hook
+	collection.mu.Unlock()
+}
+
+// DecodeError 文档解码失败, 包含集合、文档 _id 和目标类型
+type DecodeError struct {
+	Collection string
+	ID         interface{}
+	Type       reflect.Type
+	Err        error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode %s document %v into %v: %v", e.Collection, e.ID, e.Type, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decode 解码一条文档, 先执行读取时迁移, 设置了解码钩子时交给钩子处理, 失败时返回 DecodeError
+func (collection *Collection) decode(raw bson.Raw, v interface{}) error {
+	raw = collection.migrate(raw)
+	collection.mu.RLock()
+	hook := collection.decodeHook
+	collection.mu.RUnlock()
+	var err error
+	if hook != nil {
+		err = hook(raw, v)
+	} else {
+		err = unmarshal(raw, v)
+	}
+	if err == nil {
+		return nil
+	}
+	var decodeErr *DecodeError
+	if errors.As(err, &decodeErr) {
+		return err
+	}
+	decodeErr = &DecodeError{Collection: collection.namespace(), Type: reflect.TypeOf(v), Err: err}
+	if id, lookupErr := raw.LookupErr("_id"); lookupErr == nil {
+		var value interface{}
+		if unmarshalValue(id, &value) == nil {
+			decodeErr.ID = value
+		}
+	}
+	return decodeErr
+}
+
+// decodeOutside 在 run 之外解码(游标逐条解码、缓存命中), 失败时同样计入 method 的解码失败统计
+func (query *Query) decodeOutside(method string, raw bson.Raw, v interface{}) error {
+	err := query.decode(raw, v)
+	query.observeDecode(method, err)
+	return err
+}
+
+// decodeSingle 解码 FindOne 的结果
+func (collection *Collection) decodeSingle(result *mongo.SingleResult, v interface{}) error {
+	raw, err := result.Raw()
+	if err != nil {
+		return err
+	}
+	return collection.decode(raw, v)
+}
+
+// FindRaw 按链式条件查询, 返回原始 BSON 文档, 不做结构体解码
+func (query *Query) FindRaw(ctx context.Context) (results []bson.Raw, err error) {
+	err = query.do(ctx, "FindRaw", func(ctx context.Context) error {
+		cursor, err := query.Table.Find(ctx, query.filter, query.findOptions(ctx))
+		if err != nil {
+			return err
+		}
+		results = make([]bson.Raw, 0)
+		return cursor.All(ctx, &results)
+	})
+	return
+}
@@ -0,0 +1,111 @@
+package mongodb
+
+import (
+	"reflect"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// toDocument 按 bson 标签将结构体或 map 转换成 bson.M
+func toDocument(v interface{}) (bson.M, error) {
+	if m, ok := v.(bson.M); ok {
+		return m, nil
+	}
+	raw, err := marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// isZeroID _id 未赋值(nil、空字符串、零值 ObjectID)
+func isZeroID(id interface{}) bool {
+	switch v := id.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case primitive.ObjectID:
+		return v.IsZero()
+	}
+	return false
+}
+
+// withoutKeys 去掉 filter 中已经出现的顶层字段和未赋值的 _id, 用于 upsert 时的 $setOnInsert
+func withoutKeys(doc bson.M, filter bson.D) bson.M {
+	result := make(bson.M, len(doc))
+	for k, v := range doc {
+		result[k] = v
+	}
+	for _, e := range filter {
+		delete(result, e.Key)
+	}
+	if id, ok := result["_id"]; ok && isZeroID(id) {
+		delete(result, "_id")
+	}
+	return result
+}
+
+// toFloat64 将 BSON 数值转换成 float64
+func toFloat64(v interface{}) float64 {
+	switch n := v.(type) {
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	case int:
+		return float64(n)
+	case primitive.Decimal128:
+		f, _ := strconv.ParseFloat(n.String(), 64)
+		return f
+	}
+	return 0
+}
+
+// normalizeID 将 24 位十六进制字符串转换成 ObjectID, [16]byte 类型(UUID)转换成 BSON UUID,
+// 其余类型原样返回
+func normalizeID(id interface{}) interface{} {
+	switch v := id.(type) {
+	case string:
+		if oid, err := primitive.ObjectIDFromHex(v); err == nil {
+			return oid
+		}
+		return v
+	case primitive.ObjectID, primitive.Binary:
+		return v
+	}
+	val := reflect.ValueOf(id)
+	if val.Kind() == reflect.Array && val.Len() == 16 && val.Type().Elem().Kind() == reflect.Uint8 {
+		data := make([]byte, 16)
+		reflect.Copy(reflect.ValueOf(data), val)
+		return primitive.Binary{Subtype: 4, Data: data}
+	}
+	return id
+}
+
+// isIDList 判断是否为 _id 切片, []byte 视为单个值
+func isIDList(ids interface{}) bool {
+	val := reflect.ValueOf(ids)
+	return val.Kind() == reflect.Slice && val.Type().Elem().Kind() != reflect.Uint8
+}
+
+// normalizeIDs 单个 _id 或 _id 切片统一成列表
+func normalizeIDs(ids interface{}) []interface{} {
+	if isIDList(ids) {
+		val := reflect.ValueOf(ids)
+		list := make([]interface{}, 0, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			list = append(list, normalizeID(val.Index(i).Interface()))
+		}
+		return list
+	}
+	return []interface{}{normalizeID(ids)}
+}
@@ -0,0 +1,262 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultDriftSample DetectDrift 默认抽样文档数
+const DefaultDriftSample = 1000
+
+// DriftReport 抽样文档与模型结构体的差异
+type DriftReport struct {
+	Collection string
+	Sampled    int
+	// Unknown 模型中不存在的字段
+	Unknown []FieldDrift
+	// Missing 模型中必需(非指针且没有 omitempty)但文档里缺失的字段
+	Missing []FieldDrift
+	// Mismatched 类型与模型不一致的字段
+	Mismatched []FieldDrift
+}
+
+// FieldDrift 某个字段的差异, Field 为点分路径
+type FieldDrift struct {
+	Field string
+	// Expected 模型允许的 BSON 类型, Observed 实际出现的类型, 仅 Mismatched 有值
+	Expected []string
+	Observed string
+	// Count 出现该差异的文档数
+	Count int
+}
+
+// Drifted 是否存在差异
+func (report *DriftReport) Drifted() bool {
+	return len(report.Unknown) > 0 || len(report.Missing) > 0 || len(report.Mismatched) > 0
+}
+
+// modelField 模型中的一个字段
+type modelField struct {
+	types    []bsontype.Type
+	nullable bool
+	required bool
+	// children 嵌套结构体的字段, nil 表示不检查子字段
+	children map[string]*modelField
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	objectIDType = reflect.TypeOf(primitive.ObjectID{})
+	decimalType  = reflect.TypeOf(primitive.Decimal128{})
+)
+
+// DetectDrift 用 $sample 抽取 sampleSize 条满足条件的文档, 与模型结构体 model 对比字段和类型,
+// 在开启 $jsonSchema 等严格校验前检查存量数据
+func (query *Query) DetectDrift(ctx context.Context, model interface{}, sampleSize int) (*DriftReport, error) {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.New("drift model must be a struct")
+	}
+	if sampleSize <= 0 {
+		sampleSize = DefaultDriftSample
+	}
+	fields := structFields(typ)
+
+	var docs []bson.Raw
+	err := query.do(ctx, "DetectDrift", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, bson.A{
+			bson.D{{Key: "$match", Value: query.filter}},
+			bson.D{{Key: "$sample", Value: bson.D{{Key: "size", Value: sampleSize}}}},
+		})
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &docs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]map[string]*FieldDrift{"unknown": {}, "missing": {}, "mismatched": {}}
+	for _, doc := range docs {
+		compareDocument(doc, fields, "", counts)
+	}
+	return &DriftReport{
+		Collection: query.namespace(),
+		Sampled:    len(docs),
+		Unknown:    sortedDrift(counts["unknown"]),
+		Missing:    sortedDrift(counts["missing"]),
+		Mismatched: sortedDrift(counts["mismatched"]),
+	}, nil
+}
+
+// compareDocument 对比一条文档, 差异累加到 counts
+func compareDocument(doc bson.Raw, fields map[string]*modelField, prefix string, counts map[string]map[string]*FieldDrift) {
+	elements, err := doc.Elements()
+	if err != nil {
+		return
+	}
+	seen := make(map[string]bool, len(elements))
+	for _, element := range elements {
+		key := element.Key()
+		seen[key] = true
+		path := prefix + key
+		field, ok := fields[key]
+		if !ok {
+			addDrift(counts["unknown"], path, FieldDrift{Field: path})
+			continue
+		}
+		value := element.Value()
+		if value.Type == bsontype.Null && field.nullable {
+			continue
+		}
+		if !field.accepts(value.Type) {
+			expected := make([]string, len(field.types))
+			for i, t := range field.types {
+				expected[i] = t.String()
+			}
+			observed := value.Type.String()
+			addDrift(counts["mismatched"], path+"\x00"+observed, FieldDrift{Field: path, Expected: expected, Observed: observed})
+			continue
+		}
+		if field.children != nil && value.Type == bsontype.EmbeddedDocument {
+			compareDocument(value.Document(), field.children, path+".", counts)
+		}
+	}
+	for key, field := range fields {
+		if field.required && !seen[key] {
+			addDrift(counts["missing"], prefix+key, FieldDrift{Field: prefix + key})
+		}
+	}
+}
+
+func addDrift(counts map[string]*FieldDrift, key string, drift FieldDrift) {
+	if existing, ok := counts[key]; ok {
+		existing.Count++
+		return
+	}
+	drift.Count = 1
+	counts[key] = &drift
+}
+
+func sortedDrift(counts map[string]*FieldDrift) []FieldDrift {
+	list := make([]FieldDrift, 0, len(counts))
+	for _, drift := range counts {
+		list = append(list, *drift)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Field != list[j].Field {
+			return list[i].Field < list[j].Field
+		}
+		return list[i].Observed < list[j].Observed
+	})
+	return list
+}
+
+// accepts 字段是否接受该 BSON 类型, 没有类型限制(interface{})时接受任意类型
+func (field *modelField) accepts(t bsontype.Type) bool {
+	if len(field.types) == 0 {
+		return true
+	}
+	for _, allowed := range field.types {
+		if allowed == t {
+			return true
+		}
+	}
+	return false
+}
+
+// structFields 按 bson 标签解析结构体字段, 内联(inline)字段展开到当前层
+func structFields(typ reflect.Type) map[string]*modelField {
+	fields := make(map[string]*modelField)
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		key := fieldKey(sf)
+		if key == "-" {
+			continue
+		}
+		tag := sf.Tag.Get("bson")
+		if strings.Contains(tag, ",inline") {
+			inner := sf.Type
+			for inner.Kind() == reflect.Ptr {
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Struct {
+				for k, v := range structFields(inner) {
+					fields[k] = v
+				}
+			}
+			continue
+		}
+		field := modelFieldOf(sf.Type)
+		field.required = sf.Type.Kind() != reflect.Ptr && !strings.Contains(tag, ",omitempty")
+		fields[key] = field
+	}
+	return fields
+}
+
+// modelFieldOf Go 类型对应的 BSON 类型
+func modelFieldOf(typ reflect.Type) *modelField {
+	field := &modelField{}
+	for typ.Kind() == reflect.Ptr {
+		field.nullable = true
+		typ = typ.Elem()
+	}
+	switch typ {
+	case timeType:
+		field.types = []bsontype.Type{bsontype.DateTime}
+		return field
+	case objectIDType:
+		field.types = []bsontype.Type{bsontype.ObjectID}
+		return field
+	case decimalType:
+		field.types = []bsontype.Type{bsontype.Decimal128}
+		return field
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		field.types = []bsontype.Type{bsontype.String}
+	case reflect.Bool:
+		field.types = []bsontype.Type{bsontype.Boolean}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		field.types = []bsontype.Type{bsontype.Int32, bsontype.Int64}
+	case reflect.Float32, reflect.Float64:
+		field.types = []bsontype.Type{bsontype.Double, bsontype.Int32, bsontype.Int64}
+	case reflect.Slice, reflect.Array:
+		field.nullable = field.nullable || typ.Kind() == reflect.Slice
+		if typ.Elem().Kind() == reflect.Uint8 {
+			field.types = []bsontype.Type{bsontype.Binary}
+		} else {
+			field.types = []bsontype.Type{bsontype.Array}
+		}
+	case reflect.Map:
+		field.nullable = true
+		field.types = []bsontype.Type{bsontype.EmbeddedDocument}
+	case reflect.Struct:
+		field.types = []bsontype.Type{bsontype.EmbeddedDocument}
+		field.children = structFields(typ)
+	case reflect.Interface:
+		field.nullable = true
+	}
+	return field
+}
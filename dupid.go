@@ -0,0 +1,136 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// generatedID 文档的 _id 是否会由 BeforeCreate 生成
+func generatedID(document interface{}) bool {
+	val := reflect.ValueOf(document)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return false
+		}
+		val = val.Elem()
+	}
+	switch val.Kind() {
+	case reflect.Map:
+		if m, ok := val.Interface().(bson.M); ok {
+			_, exists := m["_id"]
+			return !exists
+		}
+	case reflect.Struct:
+		field := val.FieldByName("Id")
+		if !field.IsValid() {
+			return false
+		}
+		return field.Type() == reflect.TypeOf(primitive.ObjectID{}) || (field.Kind() == reflect.String && field.String() == "")
+	}
+	return false
+}
+
+// generatedIDs 批量写入时每个文档的 _id 是否由 BeforeCreate 生成
+func generatedIDs(documents interface{}) []bool {
+	val := reflect.ValueOf(documents)
+	for val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil
+	}
+	generated := make([]bool, val.Len())
+	for i := range generated {
+		generated[i] = generatedID(val.Index(i).Interface())
+	}
+	return generated
+}
+
+// duplicateIDIndex 错误是 _id 重复时返回出错文档在本次写入中的下标
+func duplicateIDIndex(err error) (int, bool) {
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) {
+		for _, e := range writeErr.WriteErrors {
+			if isDuplicateIDError(e) {
+				return e.Index, true
+			}
+		}
+	}
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+		if e := bulkErr.WriteErrors[0]; isDuplicateIDError(e.WriteError) {
+			return e.Index, true
+		}
+	}
+	return 0, false
+}
+
+func isDuplicateIDError(e mongo.WriteError) bool {
+	return e.Code == 11000 && strings.Contains(e.Message, "index: _id_ ")
+}
+
+// regenerateID 为生成的 _id 换一个新值, 保持原来的类型(ObjectID 或其字符串形式)
+func regenerateID(document interface{}) interface{} {
+	m, ok := document.(bson.M)
+	if !ok {
+		return document
+	}
+	if _, isString := m["_id"].(string); isString {
+		m["_id"] = primitive.NewObjectID().String()
+	} else {
+		m["_id"] = primitive.NewObjectID()
+	}
+	return m
+}
+
+// idRetries 生成的 _id 重复时的最大重试次数
+func (query *Query) idRetries() int {
+	if opt := query.client.opt; opt != nil {
+		return opt.RetryDuplicateID
+	}
+	return 0
+}
+
+// insertOne 写入一条文档, _id 由本包生成且重复时换一个 _id 重试
+func (query *Query) insertOne(ctx context.Context, data interface{}, generated bool) (*mongo.InsertOneResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := query.Table.InsertOne(ctx, data, options.InsertOne().SetComment(commentValue(ctx)))
+		if err == nil || !generated || attempt >= query.idRetries() {
+			return result, err
+		}
+		if _, ok := duplicateIDIndex(err); !ok {
+			return result, err
+		}
+		data = regenerateID(data)
+	}
+}
+
+// insertMany 有序写入多条文档, 某条生成的 _id 重复时换一个 _id 并从该条继续写入
+func (query *Query) insertMany(ctx context.Context, data []interface{}, generated []bool) (*mongo.InsertManyResult, error) {
+	total := &mongo.InsertManyResult{}
+	offset, retries := 0, 0
+	for {
+		result, err := query.Table.InsertMany(ctx, data[offset:], options.InsertMany().SetComment(commentValue(ctx)))
+		index, duplicate := duplicateIDIndex(err)
+		failed := offset + index
+		if err == nil || !duplicate || result == nil || retries >= query.idRetries() ||
+			failed >= len(generated) || !generated[failed] {
+			if result != nil {
+				total.InsertedIDs = append(total.InsertedIDs, result.InsertedIDs...)
+			}
+			return total, err
+		}
+		// 有序写入在出错的文档处停止, 之前的文档已经写入
+		total.InsertedIDs = append(total.InsertedIDs, result.InsertedIDs[:index]...)
+		data[failed] = regenerateID(data[failed])
+		offset = failed
+		retries++
+	}
+}
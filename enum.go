@@ -0,0 +1,193 @@
+package mongodb
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// EnumValue 可以注册为枚举的类型, 以字符串或整数为底层类型
+type EnumValue interface {
+	~string | ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint8 | ~uint16 | ~uint32
+}
+
+// EnumError 写入的值不在枚举允许的取值中
+type EnumError struct {
+	Type  reflect.Type
+	Value interface{}
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("%v is not an allowed value of enum %v", e.Value, e.Type)
+}
+
+// enumValues 已注册枚举允许的取值(以写入数据库的形式保存), 用于 SchemaFor
+var enumValues sync.Map
+
+// RegisterEnum 注册枚举类型的编解码器, 写入时不在 values 中的值返回 EnumError; 字符串枚举存为字符串, 整数枚举存为整数.
+// 读取时不校验取值, 整数枚举可以从 int32、int64 和整数值的 double 解码.
+// 编解码器注册在本包的 Registry() 上(bson.Marshal 等驱动默认的编解码不经过它), 应在程序初始化时(建立连接、执行操作之前)调用;
+// 零值不在 values 中时字段需要加 omitempty, 否则写入零值会报错:
+//
+//	type Status string
+//
+//	func init() {
+//		mongodb.RegisterEnum(StatusActive, StatusDisabled)
+//	}
+func RegisterEnum[T EnumValue](values ...T) {
+	typ := reflect.TypeOf(*new(T))
+	allowed := make(map[T]bool, len(values))
+	stored := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		allowed[value] = true
+		stored = append(stored, storedEnum(reflect.ValueOf(value)))
+	}
+	enumValues.Store(typ, stored)
+	registry.RegisterTypeEncoder(typ, bsoncodec.ValueEncoderFunc(
+		func(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+			value := val.Interface().(T)
+			if !allowed[value] {
+				return &EnumError{Type: typ, Value: value}
+			}
+			return writeEnum(vw, val)
+		}))
+	registry.RegisterTypeDecoder(typ, bsoncodec.ValueDecoderFunc(
+		func(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+			return readEnum(vr, val, nil)
+		}))
+}
+
+// RegisterNamedEnum 注册以名称存储的整数枚举, 写入时存为 names 中的名称, 不在 names 中的值返回 EnumError;
+// 读取时接受名称, 也接受改为名称存储之前写入的整数
+func RegisterNamedEnum[T ~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint8 | ~uint16 | ~uint32](names map[T]string) {
+	typ := reflect.TypeOf(*new(T))
+	values := make(map[string]int64, len(names))
+	sorted := make([]string, 0, len(names))
+	for value, name := range names {
+		values[name] = storedEnum(reflect.ValueOf(value)).(int64)
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	stored := make([]interface{}, 0, len(sorted))
+	for _, name := range sorted {
+		stored = append(stored, name)
+	}
+	enumValues.Store(typ, stored)
+	registry.RegisterTypeEncoder(typ, bsoncodec.ValueEncoderFunc(
+		func(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+			value := val.Interface().(T)
+			name, ok := names[value]
+			if !ok {
+				return &EnumError{Type: typ, Value: value}
+			}
+			return vw.WriteString(name)
+		}))
+	registry.RegisterTypeDecoder(typ, bsoncodec.ValueDecoderFunc(
+		func(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+			return readEnum(vr, val, values)
+		}))
+}
+
+// registeredEnum 已注册枚举允许的取值
+func registeredEnum(typ reflect.Type) ([]interface{}, bool) {
+	values, ok := enumValues.Load(typ)
+	if !ok {
+		return nil, false
+	}
+	return values.([]interface{}), true
+}
+
+// storedEnum 枚举值写入数据库的形式, 与 writeEnum 一致
+func storedEnum(val reflect.Value) interface{} {
+	switch val.Kind() {
+	case reflect.String:
+		return val.String()
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return int64(val.Uint())
+	default:
+		return val.Int()
+	}
+}
+
+// writeEnum 字符串写为 string, 整数在 int32 范围内写为 int32, 否则写为 int64
+func writeEnum(vw bsonrw.ValueWriter, val reflect.Value) error {
+	if val.Kind() == reflect.String {
+		return vw.WriteString(val.String())
+	}
+	n := storedEnum(val).(int64)
+	if n >= math.MinInt32 && n <= math.MaxInt32 {
+		return vw.WriteInt32(int32(n))
+	}
+	return vw.WriteInt64(n)
+}
+
+// readEnum 读取枚举值, names 不为空时整数枚举也接受名称
+func readEnum(vr bsonrw.ValueReader, val reflect.Value, names map[string]int64) error {
+	var n int64
+	switch vr.Type() {
+	case bsontype.String:
+		s, err := vr.ReadString()
+		if err != nil {
+			return err
+		}
+		if val.Kind() == reflect.String {
+			val.SetString(s)
+			return nil
+		}
+		value, ok := names[s]
+		if !ok {
+			return fmt.Errorf("cannot decode string %q into enum %v", s, val.Type())
+		}
+		n = value
+	case bsontype.Int32:
+		i, err := vr.ReadInt32()
+		if err != nil {
+			return err
+		}
+		n = int64(i)
+	case bsontype.Int64:
+		i, err := vr.ReadInt64()
+		if err != nil {
+			return err
+		}
+		n = i
+	case bsontype.Double:
+		f, err := vr.ReadDouble()
+		if err != nil {
+			return err
+		}
+		if f != math.Trunc(f) {
+			return fmt.Errorf("cannot decode double %v into enum %v", f, val.Type())
+		}
+		n = int64(f)
+	case bsontype.Null:
+		val.Set(reflect.Zero(val.Type()))
+		return vr.ReadNull()
+	case bsontype.Undefined:
+		val.Set(reflect.Zero(val.Type()))
+		return vr.ReadUndefined()
+	default:
+		return fmt.Errorf("cannot decode %v into enum %v", vr.Type(), val.Type())
+	}
+	switch val.Kind() {
+	case reflect.String:
+		return fmt.Errorf("cannot decode number %d into enum %v", n, val.Type())
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		if n < 0 || val.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%d overflows enum %v", n, val.Type())
+		}
+		val.SetUint(uint64(n))
+	default:
+		if val.OverflowInt(n) {
+			return fmt.Errorf("%d overflows enum %v", n, val.Type())
+		}
+		val.SetInt(n)
+	}
+	return nil
+}
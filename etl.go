@@ -0,0 +1,251 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Pipeline 在集合之间(可以跨连接)转换并导入数据:
+//
+//	mongodb.Pipeline{
+//		Source:    configs.GetMongoDB("old").Collection("users").Where(filter),
+//		Transform: fn,
+//		Dest:      configs.GetMongoDB("new").Collection("users"),
+//	}.Run(ctx, nil)
+type Pipeline struct {
+	Source *Query
+	// Transform 转换一条源文档, 返回 nil 表示跳过; 为空时原样写入
+	Transform func(doc bson.M) (interface{}, error)
+	Dest      *Query
+}
+
+// PipelineOptions Pipeline 运行参数
+type PipelineOptions struct {
+	// BatchSize 每批写入的文档数, 默认 500
+	BatchSize int
+	// Concurrency 同时写入的批次数, 默认 1
+	Concurrency int
+	// Upsert 按 _id 覆盖写入, 可以重复执行; 否则直接插入
+	Upsert bool
+	// Checkpoint 断点存储, 每轮批次全部成功后保存最后一个 _id, 重新运行时从断点继续
+	Checkpoint Checkpointer
+	// Progress 每轮批次完成后回调
+	Progress func(PipelineProgress)
+}
+
+// PipelineProgress Pipeline 进度
+type PipelineProgress struct {
+	Read    int64
+	Written int64
+	Skipped int64
+	LastID  interface{}
+}
+
+// Checkpointer 断点存储
+type Checkpointer interface {
+	Load(ctx context.Context) (lastID interface{}, err error)
+	Save(ctx context.Context, lastID interface{}) error
+}
+
+// collectionCheckpoint 保存在集合中的断点 {_id: name, last_id: ...}
+type collectionCheckpoint struct {
+	query *Query
+	name  string
+}
+
+// CollectionCheckpoint 使用集合保存断点, name 区分不同的任务
+func CollectionCheckpoint(query *Query, name string) Checkpointer {
+	return &collectionCheckpoint{query: query, name: name}
+}
+
+func (checkpoint *collectionCheckpoint) Load(ctx context.Context) (interface{}, error) {
+	var doc struct {
+		LastID interface{} `bson:"last_id"`
+	}
+	err := checkpoint.query.do(ctx, "CheckpointLoad", func(ctx context.Context) error {
+		return checkpoint.query.Table.FindOne(ctx, bson.D{{Key: "_id", Value: checkpoint.name}}, &options.FindOneOptions{Comment: commentOf(ctx)}).Decode(&doc)
+	})
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return doc.LastID, err
+}
+
+func (checkpoint *collectionCheckpoint) Save(ctx context.Context, lastID interface{}) error {
+	return checkpoint.query.do(ctx, "CheckpointSave", func(ctx context.Context) error {
+		_, err := checkpoint.query.Table.UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: checkpoint.name}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "last_id", Value: lastID}}}},
+			options.Update().SetUpsert(true).SetComment(commentValue(ctx)))
+		return err
+	})
+}
+
+// Run 按 _id 升序读取源集合, 转换后分批写入目标集合
+func (pipeline Pipeline) Run(ctx context.Context, opts *PipelineOptions) (PipelineProgress, error) {
+	var progress PipelineProgress
+	if pipeline.Source == nil || pipeline.Dest == nil {
+		return progress, errors.New("pipeline requires both source and destination")
+	}
+	if opts == nil {
+		opts = &PipelineOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	source := pipeline.Source.Sort(bson.D{{Key: "_id", Value: 1}})
+	if opts.Checkpoint != nil {
+		lastID, err := opts.Checkpoint.Load(ctx)
+		if err != nil {
+			return progress, err
+		}
+		if lastID != nil {
+			source = source.and(bson.E{Key: "_id", Value: bson.D{{Key: "$gt", Value: lastID}}})
+			progress.LastID = lastID
+		}
+	}
+	cursor, err := source.find(ctx, "PipelineRun")
+	if err != nil {
+		return progress, err
+	}
+	defer cursor.Close(context.Background())
+
+	for {
+		batches, lastID, err := pipeline.readRound(ctx, cursor, batchSize, concurrency, &progress)
+		if err != nil {
+			return progress, err
+		}
+		if len(batches) == 0 {
+			return progress, nil
+		}
+		if err := pipeline.writeRound(ctx, batches, opts.Upsert, &progress); err != nil {
+			return progress, err
+		}
+		progress.LastID = lastID
+		if opts.Checkpoint != nil {
+			if err := opts.Checkpoint.Save(ctx, lastID); err != nil {
+				return progress, err
+			}
+		}
+		if opts.Progress != nil {
+			opts.Progress(progress)
+		}
+	}
+}
+
+// readRound 读取并转换最多 concurrency 批文档, 返回最后读到的 _id
+func (pipeline Pipeline) readRound(ctx context.Context, cursor *Cursor, batchSize, concurrency int, progress *PipelineProgress) ([][]interface{}, interface{}, error) {
+	var (
+		batches [][]interface{}
+		batch   []interface{}
+		lastID  interface{}
+	)
+	for len(batches) < concurrency && cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, nil, err
+		}
+		progress.Read++
+		lastID = doc["_id"]
+		var out interface{} = doc
+		if pipeline.Transform != nil {
+			var err error
+			if out, err = pipeline.Transform(doc); err != nil {
+				return nil, nil, err
+			}
+		}
+		if out == nil {
+			progress.Skipped++
+		} else {
+			batch = append(batch, out)
+		}
+		if len(batch) == batchSize {
+			batches = append(batches, batch)
+			batch = nil
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	if len(batches) == 0 && lastID != nil {
+		// 整轮都被跳过, 仍然推进断点
+		batches = append(batches, nil)
+	}
+	return batches, lastID, nil
+}
+
+// writeRound 并发写入一轮批次, 任意一批失败返回错误
+func (pipeline Pipeline) writeRound(ctx context.Context, batches [][]interface{}, upsert bool, progress *PipelineProgress) error {
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		written  int64
+	)
+	for _, batch := range batches {
+		if len(batch) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(batch []interface{}) {
+			defer wg.Done()
+			n, err := pipeline.write(ctx, batch, upsert)
+			atomic.AddInt64(&written, n)
+			if err != nil {
+				once.Do(func() { firstErr = err })
+			}
+		}(batch)
+	}
+	wg.Wait()
+	progress.Written += written
+	return firstErr
+}
+
+// write 写入一批文档
+func (pipeline Pipeline) write(ctx context.Context, batch []interface{}, upsert bool) (written int64, err error) {
+	dest := pipeline.Dest
+	err = dest.do(ctx, "PipelineWrite", func(ctx context.Context) error {
+		if !upsert {
+			result, err := dest.insertMany(ctx, batch, generatedIDs(batch))
+			if result != nil {
+				written = int64(len(result.InsertedIDs))
+			}
+			return err
+		}
+		models := make([]mongo.WriteModel, 0, len(batch))
+		for _, item := range batch {
+			doc, err := toDocument(item)
+			if err != nil {
+				return err
+			}
+			if isZeroID(doc["_id"]) {
+				return errors.New("pipeline upsert requires transformed documents to keep _id")
+			}
+			models = append(models, mongo.NewReplaceOneModel().
+				SetFilter(bson.D{{Key: "_id", Value: doc["_id"]}}).
+				SetReplacement(doc).
+				SetUpsert(true))
+		}
+		result, err := dest.Table.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false).SetComment(commentValue(ctx)))
+		if result != nil {
+			written = result.UpsertedCount + result.MatchedCount
+		}
+		return err
+	})
+	return
+}
@@ -0,0 +1,50 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// FindMap 按链式条件查询, 结果以 keyField(支持 a.b 形式)的值为键放入 results 指向的 map,
+// 例如 map[primitive.ObjectID]User, 键重复时后出现的文档覆盖之前的, 缺少 keyField 的文档被忽略
+func (query *Query) FindMap(ctx context.Context, keyField string, results interface{}) error {
+	val := reflect.ValueOf(results)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Map {
+		return errors.New("results argument must be a map address")
+	}
+	if keyField == "" {
+		keyField = "_id"
+	}
+	path := strings.Split(keyField, ".")
+	mapType := val.Elem().Type()
+	return query.do(ctx, "FindMap", func(ctx context.Context) error {
+		cursor, err := query.Table.Find(ctx, query.filter, query.findOptions(ctx))
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		m := reflect.MakeMap(mapType)
+		for cursor.Next(ctx) {
+			rawKey, err := cursor.Current.LookupErr(path...)
+			if err != nil {
+				continue
+			}
+			key := reflect.New(mapType.Key())
+			if err := unmarshalValue(rawKey, key.Interface()); err != nil {
+				return err
+			}
+			item := reflect.New(mapType.Elem())
+			if err := query.decode(cursor.Current, item.Interface()); err != nil {
+				return err
+			}
+			m.SetMapIndex(key.Elem(), item.Elem())
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		val.Elem().Set(m)
+		return nil
+	})
+}
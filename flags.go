@@ -0,0 +1,120 @@
+package mongodb
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// Flag 功能开关, 存储在集合中, _id 为开关名
+type Flag struct {
+	Name    string `bson:"_id"`
+	Enabled bool   `bson:"enabled"`
+	// Match 属性白名单, 列出的每个属性都必须在允许的值中, 为空不限制
+	Match map[string][]string `bson:"match,omitempty"`
+	// Percentage 灰度百分比(1-99), 按属性 key 的哈希分流, 0 或 100 不限制
+	Percentage int `bson:"percentage,omitempty"`
+}
+
+// Flags 功能开关存储, 内存缓存 + 变更流实时刷新
+type Flags struct {
+	query  *Query
+	mu     sync.RWMutex
+	flags  map[string]Flag
+	loaded bool
+}
+
+// Flags 使用 collection 存储功能开关
+func (client *MongoDBClient) Flags(collection string) *Flags {
+	return &Flags{query: client.Collection(collection), flags: make(map[string]Flag)}
+}
+
+// Load 全量加载开关到内存
+func (flags *Flags) Load(ctx context.Context) error {
+	list, err := Find[Flag](ctx, flags.query)
+	if err != nil {
+		return err
+	}
+	m := make(map[string]Flag, len(list))
+	for _, flag := range list {
+		m[flag.Name] = flag
+	}
+	flags.mu.Lock()
+	flags.flags = m
+	flags.loaded = true
+	flags.mu.Unlock()
+	return nil
+}
+
+// Start 加载开关并在后台监听变更, 直到 ctx 取消
+func (flags *Flags) Start(ctx context.Context) error {
+	if err := flags.Load(ctx); err != nil {
+		return err
+	}
+	go flags.query.follow(ctx, "FlagsWatch", flags.Load, flags.apply)
+	return nil
+}
+
+// apply 将一条变更应用到内存缓存
+func (flags *Flags) apply(event *ChangeEvent) {
+	name, _ := event.DocumentKey["_id"].(string)
+	if name == "" {
+		return
+	}
+	flags.mu.Lock()
+	defer flags.mu.Unlock()
+	var flag Flag
+	if event.OperationType == "delete" || event.DecodeFullDocument(&flag) != nil {
+		delete(flags.flags, name)
+		return
+	}
+	flags.flags[name] = flag
+}
+
+// IsEnabled 判断开关对给定属性是否开启, 开关不存在时为关闭, 尚未加载时先加载
+func (flags *Flags) IsEnabled(ctx context.Context, name string, attrs map[string]string) bool {
+	flags.mu.RLock()
+	loaded := flags.loaded
+	flag, ok := flags.flags[name]
+	flags.mu.RUnlock()
+	if !loaded {
+		if err := flags.Load(ctx); err != nil {
+			if Log != nil {
+				Log.Warn("MongoDB功能开关加载失败->", err)
+			}
+			return false
+		}
+		flags.mu.RLock()
+		flag, ok = flags.flags[name]
+		flags.mu.RUnlock()
+	}
+	return ok && flag.Evaluate(attrs)
+}
+
+// Evaluate 按属性计算开关是否开启
+func (flag Flag) Evaluate(attrs map[string]string) bool {
+	if !flag.Enabled {
+		return false
+	}
+	for attr, allowed := range flag.Match {
+		value, ok := attrs[attr]
+		if !ok || !containsString(allowed, value) {
+			return false
+		}
+	}
+	if flag.Percentage > 0 && flag.Percentage < 100 {
+		h := fnv.New32a()
+		h.Write([]byte(flag.Name + ":" + attrs["key"]))
+		return int(h.Sum32()%100) < flag.Percentage
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
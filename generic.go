@@ -0,0 +1,37 @@
+package mongodb
+
+import "context"
+
+// Find 按链式条件查询多条数据并解码成 []T
+func Find[T any](ctx context.Context, query *Query) ([]T, error) {
+	var results []T
+	err := query.do(ctx, "Find", func(ctx context.Context) error {
+		cursor, err := query.Table.Find(ctx, query.filter, query.findOptions(ctx))
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		results = make([]T, 0)
+		for cursor.Next(ctx) {
+			var item T
+			if err := query.decode(cursor.Current, &item); err != nil {
+				return err
+			}
+			results = append(results, item)
+		}
+		return cursor.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// FindOne 按链式条件查询一条数据并解码成 T
+func FindOne[T any](ctx context.Context, query *Query) (T, error) {
+	var result T
+	err := query.do(ctx, "FindOne", func(ctx context.Context) error {
+		return query.decodeSingle(query.Table.FindOne(ctx, query.filter, query.findOneOptions(ctx)), &result)
+	})
+	return result, err
+}
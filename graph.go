@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GraphLookup $graphLookup 阶段, 在 From 集合中递归查找 ConnectFromField -> ConnectToField 关联的文档
+type GraphLookup struct {
+	From string
+	// StartWith 起点表达式, 如 "$_id"
+	StartWith        interface{}
+	ConnectFromField string
+	ConnectToField   string
+	As               string
+	// MaxDepth 最大递归深度, nil 不限制, 0 只查找直接关联的文档
+	MaxDepth *int64
+	// DepthField 记录递归深度的字段, 为空不记录
+	DepthField string
+	// Restrict 递归时对关联文档附加的条件
+	Restrict bson.D
+}
+
+// Stage 构造 $graphLookup 阶段
+func (lookup GraphLookup) Stage() bson.D {
+	graph := bson.D{
+		{Key: "from", Value: lookup.From},
+		{Key: "startWith", Value: lookup.StartWith},
+		{Key: "connectFromField", Value: lookup.ConnectFromField},
+		{Key: "connectToField", Value: lookup.ConnectToField},
+		{Key: "as", Value: lookup.As},
+	}
+	if lookup.MaxDepth != nil {
+		graph = append(graph, bson.E{Key: "maxDepth", Value: *lookup.MaxDepth})
+	}
+	if lookup.DepthField != "" {
+		graph = append(graph, bson.E{Key: "depthField", Value: lookup.DepthField})
+	}
+	if len(lookup.Restrict) > 0 {
+		graph = append(graph, bson.E{Key: "restrictSearchWithMatch", Value: lookup.Restrict})
+	}
+	return bson.D{{Key: "$graphLookup", Value: graph}}
+}
+
+// TreeOptions 树形集合的结构
+type TreeOptions struct {
+	// ParentField 保存父节点 _id 的字段, 默认 parent_id
+	ParentField string
+	// MaxDepth 最大深度, nil 不限制, 0 只查找直接子节点
+	MaxDepth *int64
+	// DepthField 结果中记录深度(直接子节点为 0)的字段, 默认 depth
+	DepthField string
+}
+
+// FindDescendants 查找 rootID 的全部后代节点(父节点字段为 parent_id), 按深度升序解码到 results(切片指针)
+func (query *Query) FindDescendants(ctx context.Context, rootID interface{}, results interface{}) error {
+	return query.FindDescendantsBy(ctx, rootID, TreeOptions{}, results)
+}
+
+// FindDescendantsBy 按 opts 描述的树形结构查找 rootID 的后代节点, 链式条件作为后代节点的过滤条件,
+// 不满足条件的节点及其子树不会被遍历
+func (query *Query) FindDescendantsBy(ctx context.Context, rootID interface{}, opts TreeOptions, results interface{}) error {
+	if opts.ParentField == "" {
+		opts.ParentField = "parent_id"
+	}
+	if opts.DepthField == "" {
+		opts.DepthField = "depth"
+	}
+	lookup := GraphLookup{
+		From:             query.Table.Name(),
+		StartWith:        "$_id",
+		ConnectFromField: "_id",
+		ConnectToField:   opts.ParentField,
+		As:               "descendants",
+		MaxDepth:         opts.MaxDepth,
+		DepthField:       opts.DepthField,
+		Restrict:         query.filter,
+	}
+	return query.do(ctx, "FindDescendants", func(ctx context.Context) error {
+		// 子孙文档由 $graphLookup 读取, 替换为根文档之后再按 ReadPolicy 裁剪
+		pipeline := bson.A{
+			bson.D{{Key: "$match", Value: bson.D{{Key: "_id", Value: normalizeID(rootID)}}}},
+			lookup.Stage(),
+			bson.D{{Key: "$unwind", Value: "$descendants"}},
+			bson.D{{Key: "$replaceRoot", Value: bson.D{{Key: "newRoot", Value: "$descendants"}}}},
+		}
+		pipeline = append(pipeline, query.readStages(ctx)...)
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: opts.DepthField, Value: 1}, {Key: "_id", Value: 1}}}})
+		cursor, err := query.Table.Aggregate(ctx, pipeline, query.aggregateOptions(ctx))
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, results)
+	})
+}
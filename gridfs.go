@@ -0,0 +1,422 @@
+package mongodb
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultChunkBytes GridFS 默认分块大小 255KB
+const DefaultChunkBytes = 255 * 1024
+
+// ErrChecksumMismatch 上传或校验时内容摘要与期望值不一致
+var ErrChecksumMismatch = errors.New("mongodb: gridfs checksum mismatch")
+
+// Bucket 兼容 GridFS 规范的文件存储, 文件保存在 <name>.files 与 <name>.chunks 中,
+// 未完成的断点续传会话保存在 <name>.uploads 中
+type Bucket struct {
+	files   *Query
+	chunks  *Query
+	uploads *Query
+	mu      sync.Mutex
+	indexed bool
+}
+
+// FileInfo GridFS 文件信息
+type FileInfo struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Filename   string             `bson:"filename"`
+	Length     int64              `bson:"length"`
+	ChunkSize  int32              `bson:"chunkSize"`
+	UploadDate time.Time          `bson:"uploadDate"`
+	MD5        string             `bson:"md5,omitempty"`
+	SHA256     string             `bson:"sha256,omitempty"`
+	Metadata   bson.Raw           `bson:"metadata,omitempty"`
+}
+
+// Checksums 期望的内容摘要(十六进制), 为空的项不校验
+type Checksums struct {
+	MD5    string
+	SHA256 string
+}
+
+// Bucket 得到 GridFS 存储桶, name 为空时使用 fs
+func (client *MongoDBClient) Bucket(name string) *Bucket {
+	if name == "" {
+		name = "fs"
+	}
+	return &Bucket{
+		files:   client.Collection(name + ".files"),
+		chunks:  client.Collection(name + ".chunks"),
+		uploads: client.Collection(name + ".uploads"),
+	}
+}
+
+// ensureIndexes 首次上传时创建 GridFS 规范要求的索引, 失败时下次重试
+func (bucket *Bucket) ensureIndexes(ctx context.Context) error {
+	bucket.mu.Lock()
+	defer bucket.mu.Unlock()
+	if bucket.indexed {
+		return nil
+	}
+	if _, err := bucket.chunks.createIndex(ctx, "GridFSIndex", mongo.IndexModel{
+		Keys:    bson.D{{Key: "files_id", Value: 1}, {Key: "n", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		return err
+	}
+	if _, err := bucket.files.createIndex(ctx, "GridFSIndex", mongo.IndexModel{
+		Keys: bson.D{{Key: "filename", Value: 1}, {Key: "uploadDate", Value: 1}},
+	}); err != nil {
+		return err
+	}
+	bucket.indexed = true
+	return nil
+}
+
+// Find 按条件查询文件信息, 可以使用 metadata.xxx 查询元数据
+func (bucket *Bucket) Find(ctx context.Context, filter bson.D) (files []FileInfo, err error) {
+	if filter == nil {
+		filter = bson.D{}
+	}
+	err = bucket.files.do(ctx, "GridFSFind", func(ctx context.Context) error {
+		cursor, err := bucket.files.Table.Find(ctx, filter, &options.FindOptions{Sort: bson.D{{Key: "uploadDate", Value: 1}}, Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		files = make([]FileInfo, 0)
+		return cursor.All(ctx, &files)
+	})
+	return
+}
+
+// Stat 获取单个文件信息
+func (bucket *Bucket) Stat(ctx context.Context, id primitive.ObjectID) (*FileInfo, error) {
+	var file FileInfo
+	err := bucket.files.do(ctx, "GridFSStat", func(ctx context.Context) error {
+		return bucket.files.Table.FindOne(ctx, bson.D{{Key: "_id", Value: id}}, &options.FindOneOptions{Comment: commentOf(ctx)}).Decode(&file)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Download 按顺序读取文件的全部分块写入 w, 返回写入的字节数
+func (bucket *Bucket) Download(ctx context.Context, id primitive.ObjectID, w io.Writer) (written int64, err error) {
+	file, err := bucket.Stat(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	// 下载可能持续很久, Shutdown 时取消
+	ctx, cancel := bucket.chunks.client.streamContext(ctx)
+	defer cancel()
+	err = bucket.chunks.run(ctx, "GridFSDownload", func(ctx context.Context) error {
+		cursor, err := bucket.chunks.Table.Find(ctx, bson.D{{Key: "files_id", Value: id}},
+			&options.FindOptions{Sort: bson.D{{Key: "n", Value: 1}}, Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		var expected int32
+		for cursor.Next(ctx) {
+			var chunk struct {
+				N    int32  `bson:"n"`
+				Data []byte `bson:"data"`
+			}
+			if err := cursor.Decode(&chunk); err != nil {
+				return err
+			}
+			if chunk.N != expected {
+				return fmt.Errorf("gridfs file %s: missing chunk %d", id.Hex(), expected)
+			}
+			expected++
+			n, err := w.Write(chunk.Data)
+			written += int64(n)
+			if err != nil {
+				return err
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		if written != file.Length {
+			return fmt.Errorf("gridfs file %s: read %d of %d bytes", id.Hex(), written, file.Length)
+		}
+		return nil
+	})
+	return
+}
+
+// Verify 重新读取文件内容计算摘要, 与上传时记录的 MD5/SHA256 比较
+func (bucket *Bucket) Verify(ctx context.Context, id primitive.ObjectID) error {
+	file, err := bucket.Stat(ctx, id)
+	if err != nil {
+		return err
+	}
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if _, err := bucket.Download(ctx, id, io.MultiWriter(md5Hash, sha256Hash)); err != nil {
+		return err
+	}
+	return checkSums(Checksums{MD5: file.MD5, SHA256: file.SHA256}, md5Hash, sha256Hash)
+}
+
+// Delete 删除文件及其分块
+func (bucket *Bucket) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := bucket.chunks.do(ctx, "GridFSDelete", func(ctx context.Context) error {
+		_, err := bucket.chunks.Table.DeleteMany(ctx, bson.D{{Key: "files_id", Value: id}}, options.Delete().SetComment(commentValue(ctx)))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bucket.files.do(ctx, "GridFSDelete", func(ctx context.Context) error {
+		result, err := bucket.files.Table.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}}, options.Delete().SetComment(commentValue(ctx)))
+		if err == nil && result.DeletedCount == 0 {
+			return mongo.ErrNoDocuments
+		}
+		return err
+	})
+}
+
+// Upload 断点续传的上传会话, 已写入的分块和摘要状态保存在数据库中,
+// 连接中断后通过 ResumeUpload 从 Offset 处继续写入
+type Upload struct {
+	bucket *Bucket
+	state  uploadState
+}
+
+// uploadState <name>.uploads 中保存的会话状态
+type uploadState struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	Filename  string             `bson:"filename"`
+	ChunkSize int32              `bson:"chunkSize"`
+	Metadata  interface{}        `bson:"metadata,omitempty"`
+	// Length 已经持久化的字节数(包括 Pending)
+	Length int64 `bson:"length"`
+	// N 下一个分块序号
+	N int32 `bson:"n"`
+	// Pending 不足一个分块的剩余数据, 完成时写入最后一个分块
+	Pending   []byte    `bson:"pending"`
+	MD5       []byte    `bson:"md5State"`
+	SHA256    []byte    `bson:"sha256State"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// StartUpload 开始一次断点续传上传, chunkSize <= 0 时使用 DefaultChunkBytes
+func (bucket *Bucket) StartUpload(ctx context.Context, filename string, chunkSize int32, metadata interface{}) (*Upload, error) {
+	if err := bucket.ensureIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkBytes
+	}
+	upload := &Upload{bucket: bucket, state: uploadState{
+		ID:        primitive.NewObjectID(),
+		Filename:  filename,
+		ChunkSize: chunkSize,
+		Metadata:  metadata,
+		Pending:   []byte{},
+	}}
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if err := upload.saveHashes(md5Hash, sha256Hash); err != nil {
+		return nil, err
+	}
+	upload.state.UpdatedAt = time.Now()
+	err := bucket.uploads.do(ctx, "GridFSStartUpload", func(ctx context.Context) error {
+		_, err := bucket.uploads.Table.InsertOne(ctx, upload.state, options.InsertOne().SetComment(commentValue(ctx)))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// ResumeUpload 恢复未完成的上传会话
+func (bucket *Bucket) ResumeUpload(ctx context.Context, id primitive.ObjectID) (*Upload, error) {
+	upload := &Upload{bucket: bucket}
+	err := bucket.uploads.do(ctx, "GridFSResumeUpload", func(ctx context.Context) error {
+		return bucket.uploads.Table.FindOne(ctx, bson.D{{Key: "_id", Value: id}}, &options.FindOneOptions{Comment: commentOf(ctx)}).Decode(&upload.state)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// ID 文件 _id, 完成后即为 FileInfo.ID
+func (upload *Upload) ID() primitive.ObjectID {
+	return upload.state.ID
+}
+
+// Offset 已经持久化的字节数, 续传时从该位置继续发送
+func (upload *Upload) Offset() int64 {
+	return upload.state.Length
+}
+
+// WritePart 写入一段数据, 满一个分块就写入 chunks, 全部写完后保存会话状态.
+// 返回错误时会话停留在上次成功保存的位置, 调用方按 Offset 重新发送即可
+func (upload *Upload) WritePart(ctx context.Context, r io.Reader) (int64, error) {
+	md5Hash, sha256Hash, err := upload.loadHashes()
+	if err != nil {
+		return 0, err
+	}
+	state := upload.state
+	buf := append(make([]byte, 0, int(state.ChunkSize)), state.Pending...)
+	var read int64
+	for {
+		n, err := io.ReadFull(r, buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		read += int64(n)
+		md5Hash.Write(buf[len(buf)-n:])
+		sha256Hash.Write(buf[len(buf)-n:])
+		if len(buf) == cap(buf) {
+			if werr := upload.writeChunk(ctx, state.N, buf); werr != nil {
+				return read, werr
+			}
+			state.N++
+			buf = buf[:0]
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return read, err
+		}
+	}
+	state.Length += read
+	state.Pending = append([]byte{}, buf...)
+	state.UpdatedAt = time.Now()
+	previous := upload.state
+	upload.state = state
+	if err := upload.saveHashes(md5Hash, sha256Hash); err != nil {
+		upload.state = previous
+		return read, err
+	}
+	err = upload.bucket.uploads.do(ctx, "GridFSWritePart", func(ctx context.Context) error {
+		_, err := upload.bucket.uploads.Table.ReplaceOne(ctx, bson.D{{Key: "_id", Value: state.ID}}, upload.state, options.Replace().SetComment(commentValue(ctx)))
+		return err
+	})
+	if err != nil {
+		upload.state = previous
+	}
+	return read, err
+}
+
+// Complete 写入最后一个分块并生成 files 文档, expect 非空时校验摘要, 不一致返回 ErrChecksumMismatch 且不生成文件
+func (upload *Upload) Complete(ctx context.Context, expect Checksums) (*FileInfo, error) {
+	md5Hash, sha256Hash, err := upload.loadHashes()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSums(expect, md5Hash, sha256Hash); err != nil {
+		return nil, err
+	}
+	state := upload.state
+	if len(state.Pending) > 0 {
+		if err := upload.writeChunk(ctx, state.N, state.Pending); err != nil {
+			return nil, err
+		}
+	}
+	file := bson.D{
+		{Key: "_id", Value: state.ID},
+		{Key: "length", Value: state.Length},
+		{Key: "chunkSize", Value: state.ChunkSize},
+		{Key: "uploadDate", Value: time.Now()},
+		{Key: "filename", Value: state.Filename},
+		{Key: "md5", Value: hex.EncodeToString(md5Hash.Sum(nil))},
+		{Key: "sha256", Value: hex.EncodeToString(sha256Hash.Sum(nil))},
+	}
+	if state.Metadata != nil {
+		file = append(file, bson.E{Key: "metadata", Value: state.Metadata})
+	}
+	err = upload.bucket.files.do(ctx, "GridFSComplete", func(ctx context.Context) error {
+		_, err := upload.bucket.files.Table.ReplaceOne(ctx, bson.D{{Key: "_id", Value: state.ID}}, file, options.Replace().SetUpsert(true).SetComment(commentValue(ctx)))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = upload.bucket.uploads.do(ctx, "GridFSComplete", func(ctx context.Context) error {
+		_, err := upload.bucket.uploads.Table.DeleteOne(ctx, bson.D{{Key: "_id", Value: state.ID}}, options.Delete().SetComment(commentValue(ctx)))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return upload.bucket.Stat(ctx, state.ID)
+}
+
+// Abort 放弃上传, 删除已写入的分块和会话
+func (upload *Upload) Abort(ctx context.Context) error {
+	id := upload.state.ID
+	err := upload.bucket.chunks.do(ctx, "GridFSAbort", func(ctx context.Context) error {
+		_, err := upload.bucket.chunks.Table.DeleteMany(ctx, bson.D{{Key: "files_id", Value: id}}, options.Delete().SetComment(commentValue(ctx)))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return upload.bucket.uploads.do(ctx, "GridFSAbort", func(ctx context.Context) error {
+		_, err := upload.bucket.uploads.Table.DeleteOne(ctx, bson.D{{Key: "_id", Value: id}}, options.Delete().SetComment(commentValue(ctx)))
+		return err
+	})
+}
+
+// writeChunk 按 files_id + n 覆盖写入分块, 重复发送同一分块是幂等的
+func (upload *Upload) writeChunk(ctx context.Context, n int32, data []byte) error {
+	chunks := upload.bucket.chunks
+	filter := bson.D{{Key: "files_id", Value: upload.state.ID}, {Key: "n", Value: n}}
+	return chunks.do(ctx, "GridFSWriteChunk", func(ctx context.Context) error {
+		_, err := chunks.Table.ReplaceOne(ctx, filter,
+			bson.D{{Key: "files_id", Value: upload.state.ID}, {Key: "n", Value: n}, {Key: "data", Value: data}},
+			options.Replace().SetUpsert(true).SetComment(commentValue(ctx)))
+		return err
+	})
+}
+
+// loadHashes 从会话状态恢复摘要计算的中间状态
+func (upload *Upload) loadHashes() (hash.Hash, hash.Hash, error) {
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if err := md5Hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.state.MD5); err != nil {
+		return nil, nil, err
+	}
+	if err := sha256Hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(upload.state.SHA256); err != nil {
+		return nil, nil, err
+	}
+	return md5Hash, sha256Hash, nil
+}
+
+// saveHashes 保存摘要计算的中间状态到会话
+func (upload *Upload) saveHashes(md5Hash, sha256Hash hash.Hash) (err error) {
+	if upload.state.MD5, err = md5Hash.(encoding.BinaryMarshaler).MarshalBinary(); err != nil {
+		return
+	}
+	upload.state.SHA256, err = sha256Hash.(encoding.BinaryMarshaler).MarshalBinary()
+	return
+}
+
+// checkSums 比较摘要, 期望值为空的项跳过
+func checkSums(expect Checksums, md5Hash, sha256Hash hash.Hash) error {
+	if expect.MD5 != "" && expect.MD5 != hex.EncodeToString(md5Hash.Sum(nil)) {
+		return fmt.Errorf("%w: md5", ErrChecksumMismatch)
+	}
+	if expect.SHA256 != "" && expect.SHA256 != hex.EncodeToString(sha256Hash.Sum(nil)) {
+		return fmt.Errorf("%w: sha256", ErrChecksumMismatch)
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package mongodb
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LivenessOptions 后台存活检查参数
+type LivenessOptions struct {
+	// Interval 检查间隔, 默认 10 秒
+	Interval time.Duration
+	// Timeout 单次 ping 超时, 默认 2 秒
+	Timeout time.Duration
+	// OnUnhealthy 连接由健康变为不健康时回调
+	OnUnhealthy func(name string, err error)
+	// OnRecover 连接由不健康恢复时回调
+	OnRecover func(name string)
+	// Reconnect 每次检查时重新建立已配置但建立失败(如启动时服务器不可达)的连接;
+	// 已建立连接的断线重连由驱动自动完成
+	Reconnect bool
+}
+
+// StartLiveness 启动后台存活检查, 定期 ping 所有已建立的连接, 状态变化时回调, 返回停止函数
+func (configs *Configs) StartLiveness(opts LivenessOptions) (stop func()) {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 2 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		unhealthy := make(map[string]bool)
+		ticker := time.NewTicker(opts.Interval)
+		defer ticker.Stop()
+		for {
+			configs.checkLiveness(ctx, opts, unhealthy)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
+
+// checkLiveness ping 每个连接并对比上一次的状态
+func (configs *Configs) checkLiveness(ctx context.Context, opts LivenessOptions, unhealthy map[string]bool) {
+	configs.mu.RLock()
+	clients := make(map[string]*MongoDBClient, len(configs.connections))
+	for name, client := range configs.connections {
+		clients[name] = client
+	}
+	configs.mu.RUnlock()
+
+	for name, client := range clients {
+		pingCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		err := client.Ping(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		switch {
+		case err != nil && !unhealthy[name]:
+			unhealthy[name] = true
+			if Log != nil {
+				Log.Error("MongoDB连接不可用->", name, " ", err, labelSuffix(client.Labels()))
+			}
+			if opts.OnUnhealthy != nil {
+				opts.OnUnhealthy(name, err)
+			}
+		case err == nil && unhealthy[name]:
+			delete(unhealthy, name)
+			if Log != nil {
+				Log.Info("MongoDB连接已恢复->", name, labelSuffix(client.Labels()))
+			}
+			if opts.OnRecover != nil {
+				opts.OnRecover(name)
+			}
+		}
+	}
+	if opts.Reconnect {
+		configs.reconnect(ctx, unhealthy, opts)
+	}
+}
+
+// reconnect 重新建立已配置但没有成功建立的连接, 成功时按恢复处理
+func (configs *Configs) reconnect(ctx context.Context, unhealthy map[string]bool, opts LivenessOptions) {
+	configs.mu.RLock()
+	pending := make(map[string]*Opt)
+	for name, config := range configs.opt {
+		if _, ok := configs.connections[name]; !ok {
+			if _, failed := configs.connectErrors[name]; failed {
+				pending[name] = config
+			}
+		}
+	}
+	collector := configs.metrics
+	configs.mu.RUnlock()
+
+	for name, config := range pending {
+		if ctx.Err() != nil {
+			return
+		}
+		db, err := connect(config, name, collector, true)
+		if err != nil {
+			configs.connectFailed(name, err)
+			continue
+		}
+		configs.register(name, db)
+		delete(unhealthy, name)
+		if Log != nil {
+			Log.Info("MongoDB连接已建立->", name, labelSuffix(config.Labels))
+		}
+		if opts.OnRecover != nil {
+			opts.OnRecover(name)
+		}
+	}
+}
+
+// HealthCheck 启动后台健康检查, 每隔 interval ping 所有连接并记录状态(通过 Status 获取), 同时重新建立启动时失败的连接,
+// 返回停止函数
+func (configs *Configs) HealthCheck(interval time.Duration) (stop func()) {
+	return configs.StartLiveness(LivenessOptions{Interval: interval, Reconnect: true})
+}
+
+// healthState 最近一次 Ping 的结果
+type healthState struct {
+	err     error
+	checked time.Time
+}
+
+// Ping 检查服务器是否可达, 结果记录到 Status
+func (client *MongoDBClient) Ping(ctx context.Context) error {
+	err := client.Client.Ping(ctx, nil)
+	client.health.Store(healthState{err: err, checked: time.Now()})
+	return err
+}
+
+// ConnectionStatus 连接的健康状态
+type ConnectionStatus struct {
+	Name string `json:"name"`
+	// Connected 连接是否已建立, 建立失败时 Error 为失败原因
+	Connected bool `json:"connected"`
+	// Healthy 最近一次 Ping 成功, 还没有 Ping 过时为 false
+	Healthy     bool              `json:"healthy"`
+	Error       string            `json:"error,omitempty"`
+	CheckedAt   time.Time         `json:"checked_at,omitempty"`
+	Maintenance bool              `json:"maintenance"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// Status 已配置连接的健康状态(按名称排序), 基于 Ping 或 HealthCheck 记录的结果, 本身不访问服务器, 可以直接用于就绪探针
+func (configs *Configs) Status() []ConnectionStatus {
+	configs.mu.RLock()
+	defer configs.mu.RUnlock()
+	names := make(map[string]bool, len(configs.opt)+len(configs.connections))
+	for name := range configs.opt {
+		names[name] = true
+	}
+	for name := range configs.connections {
+		names[name] = true
+	}
+	statuses := make([]ConnectionStatus, 0, len(names))
+	for name := range names {
+		status := ConnectionStatus{Name: name, Maintenance: configs.maintenance[name]}
+		if config, ok := configs.opt[name]; ok {
+			status.Labels = config.Labels
+		}
+		if client, ok := configs.connections[name]; ok {
+			status.Connected = true
+			status.Maintenance = client.InMaintenance()
+			if state, ok := client.health.Load().(healthState); ok {
+				status.Healthy = state.err == nil
+				status.CheckedAt = state.checked
+				if state.err != nil {
+					status.Error = state.err.Error()
+				}
+			}
+		} else if err := configs.connectErrors[name]; err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
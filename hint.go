@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Hint 指定使用的索引(索引名或索引键文档, 如 bson.D{{Key: "status", Value: 1}}), 用于固定执行计划,
+// 对 Find 系列和 Aggregate 系列操作生效
+func (query *Query) Hint(hint interface{}) *Query {
+	query = query.clone()
+	query.hint = hint
+	return query
+}
+
+// Let 定义聚合管道中可以通过 $$name 引用的变量, 例如 bson.M{"minAge": 18} 配合 {$expr: {$gte: ["$age", "$$minAge"]}}.
+// 对 Aggregate 系列操作生效, Find 系列需要 MongoDB 5.0 以上
+func (query *Query) Let(vars interface{}) *Query {
+	query = query.clone()
+	query.let = vars
+	return query
+}
+
+// aggregateOptions 链式条件对应的聚合参数
+func (query *Query) aggregateOptions(ctx context.Context) *options.AggregateOptions {
+	return &options.AggregateOptions{
+		Comment:   commentOf(ctx),
+		Collation: query.collation,
+		Hint:      query.hint,
+		Let:       query.let,
+	}
+}
+
+// MinBound 索引扫描的下界(包含), 如 bson.D{{Key: "created_at", Value: t}, {Key: "_id", Value: id}},
+// 字段须与 Hint 指定的索引键一致, 用于复合键上的范围翻页. Min 已用于求最小值, 故以 MinBound 命名
+func (query *Query) MinBound(bound interface{}) *Query {
+	query = query.clone()
+	query.minBound = bound
+	return query
+}
+
+// MaxBound 索引扫描的上界(不包含), 要求同 MinBound
+func (query *Query) MaxBound(bound interface{}) *Query {
+	query = query.clone()
+	query.maxBound = bound
+	return query
+}
+
+// ReturnKey 只返回索引键而不是文档, 用于检查范围查询扫描的键
+func (query *Query) ReturnKey() *Query {
+	query = query.clone()
+	query.returnKey = true
+	return query
+}
@@ -0,0 +1,141 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// otherBucket $bucket 中落在边界之外的值所在的桶
+const otherBucket = "other"
+
+// HistogramBucket 数值直方图的一个桶, 范围 [Min, Max)
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int64   `json:"count"`
+	// Other 落在所有边界之外(或不是数值)的文档, 此时 Min/Max 为 0
+	Other bool `json:"other,omitempty"`
+}
+
+// BucketStage 按 boundaries 分桶计数的 $bucket 阶段, 边界之外的值归入 defaultBucket(为 nil 时这些值会导致聚合出错)
+func BucketStage(field string, boundaries []float64, defaultBucket interface{}) bson.D {
+	bounds := make(bson.A, len(boundaries))
+	for i, bound := range boundaries {
+		bounds[i] = bound
+	}
+	bucket := bson.D{
+		{Key: "groupBy", Value: "$" + field},
+		{Key: "boundaries", Value: bounds},
+	}
+	if defaultBucket != nil {
+		bucket = append(bucket, bson.E{Key: "default", Value: defaultBucket})
+	}
+	bucket = append(bucket, bson.E{Key: "output", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}})
+	return bson.D{{Key: "$bucket", Value: bucket}}
+}
+
+// BucketAutoStage 自动划分 buckets 个桶的 $bucketAuto 阶段, granularity 为空或 R5、1-2-5、POWERSOF2 等首选数列
+func BucketAutoStage(field string, buckets int, granularity string) bson.D {
+	bucket := bson.D{
+		{Key: "groupBy", Value: "$" + field},
+		{Key: "buckets", Value: buckets},
+		{Key: "output", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}},
+	}
+	if granularity != "" {
+		bucket = append(bucket, bson.E{Key: "granularity", Value: granularity})
+	}
+	return bson.D{{Key: "$bucketAuto", Value: bucket}}
+}
+
+// Histogram 按升序边界 boundaries 统计满足条件的文档 field 字段的分布,
+// 边界之外的值计入 Other 桶(有这类文档时位于结果最后)
+func (query *Query) Histogram(ctx context.Context, field string, boundaries []float64) (buckets []HistogramBucket, err error) {
+	if len(boundaries) < 2 {
+		return nil, errors.New("histogram requires at least two boundaries")
+	}
+	err = query.do(ctx, "Histogram", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, bson.A{
+			bson.D{{Key: "$match", Value: query.filter}},
+			BucketStage(field, boundaries, otherBucket),
+		})
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{Collation: query.collation, Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		var results []struct {
+			ID    interface{} `bson:"_id"`
+			Count int64       `bson:"count"`
+		}
+		if err := cursor.All(ctx, &results); err != nil {
+			return err
+		}
+		buckets = make([]HistogramBucket, 0, len(results))
+		var other *HistogramBucket
+		for _, result := range results {
+			if result.ID == otherBucket {
+				other = &HistogramBucket{Count: result.Count, Other: true}
+				continue
+			}
+			min := toFloat64(result.ID)
+			bucket := HistogramBucket{Min: min, Max: boundaries[len(boundaries)-1], Count: result.Count}
+			for _, bound := range boundaries {
+				if bound > min {
+					bucket.Max = bound
+					break
+				}
+			}
+			buckets = append(buckets, bucket)
+		}
+		if other != nil {
+			buckets = append(buckets, *other)
+		}
+		return nil
+	})
+	return
+}
+
+// AutoHistogram 由服务器自动划分 n 个文档数尽量均匀的桶统计 field 字段的分布
+func (query *Query) AutoHistogram(ctx context.Context, field string, n int, granularity string) (buckets []HistogramBucket, err error) {
+	if n <= 0 {
+		return nil, errors.New("histogram requires a positive bucket count")
+	}
+	err = query.do(ctx, "AutoHistogram", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, bson.A{
+			bson.D{{Key: "$match", Value: query.filter}},
+			BucketAutoStage(field, n, granularity),
+		})
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{Collation: query.collation, Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		var results []struct {
+			ID struct {
+				Min interface{} `bson:"min"`
+				Max interface{} `bson:"max"`
+			} `bson:"_id"`
+			Count int64 `bson:"count"`
+		}
+		if err := cursor.All(ctx, &results); err != nil {
+			return err
+		}
+		buckets = make([]HistogramBucket, 0, len(results))
+		for _, result := range results {
+			buckets = append(buckets, HistogramBucket{
+				Min:   toFloat64(result.ID.Min),
+				Max:   toFloat64(result.ID.Max),
+				Count: result.Count,
+			})
+		}
+		return nil
+	})
+	return
+}
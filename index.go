@@ -0,0 +1,250 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// createIndex 创建索引, 不附加超时, 大集合建索引耗时较长
+func (query *Query) createIndex(ctx context.Context, method string, model mongo.IndexModel) (name string, err error) {
+	err = query.run(ctx, method, func(ctx context.Context) (err error) {
+		name, err = query.Table.Indexes().CreateOne(ctx, model)
+		return
+	})
+	return
+}
+
+// validateHashedIndex 哈希索引不支持唯一约束和 TTL
+func validateHashedIndex(op *options.IndexOptions) error {
+	if op == nil {
+		return nil
+	}
+	if op.Unique != nil && *op.Unique {
+		return errors.New("hashed index does not support unique")
+	}
+	if op.ExpireAfterSeconds != nil {
+		return errors.New("hashed index does not support expireAfterSeconds")
+	}
+	return nil
+}
+
+// CreateHashedIndex 创建单字段哈希索引, 用于哈希分片键
+func (query *Query) CreateHashedIndex(ctx context.Context, field string, op ...*options.IndexOptions) (string, error) {
+	var indexOptions *options.IndexOptions
+	if len(op) > 0 {
+		indexOptions = op[0]
+	}
+	if err := validateHashedIndex(indexOptions); err != nil {
+		return "", err
+	}
+	return query.createIndex(ctx, "CreateHashedIndex", mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: "hashed"}},
+		Options: indexOptions,
+	})
+}
+
+// CreateWildcardIndex 创建通配符索引, path 为空时为 $**, 否则为 path.$**,
+// projection 为 wildcardProjection, 只能用于 $** 索引
+func (query *Query) CreateWildcardIndex(ctx context.Context, path string, projection bson.D, op ...*options.IndexOptions) (string, error) {
+	indexOptions := options.Index()
+	if len(op) > 0 && op[0] != nil {
+		indexOptions = op[0]
+	}
+	if indexOptions.Unique != nil && *indexOptions.Unique {
+		return "", errors.New("wildcard index does not support unique")
+	}
+	key := "$**"
+	if path != "" {
+		if len(projection) > 0 {
+			return "", errors.New("wildcardProjection is only allowed on the $** index")
+		}
+		key = path + ".$**"
+	}
+	if len(projection) > 0 {
+		indexOptions.SetWildcardProjection(projection)
+	}
+	return query.createIndex(ctx, "CreateWildcardIndex", mongo.IndexModel{
+		Keys:    bson.D{{Key: key, Value: 1}},
+		Options: indexOptions,
+	})
+}
+
+// CaseInsensitiveCollation 不区分大小写的排序规则(strength 2)
+var CaseInsensitiveCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// CreateCaseInsensitiveIndex 创建不区分大小写的索引, 查询时需要链式调用 CaseInsensitive() 才会使用该索引
+func (query *Query) CreateCaseInsensitiveIndex(ctx context.Context, fields []string, unique bool) (string, error) {
+	if len(fields) == 0 {
+		return "", errors.New("case-insensitive index requires at least one field")
+	}
+	keys := make(bson.D, 0, len(fields))
+	for _, field := range fields {
+		keys = append(keys, bson.E{Key: field, Value: 1})
+	}
+	return query.createIndex(ctx, "CreateCaseInsensitiveIndex", mongo.IndexModel{
+		Keys:    keys,
+		Options: options.Index().SetUnique(unique).SetCollation(CaseInsensitiveCollation),
+	})
+}
+
+// IndexBuilder 索引构建器
+type IndexBuilder struct {
+	query   *Query
+	keys    bson.D
+	options *options.IndexOptions
+}
+
+// Index 开始构建索引, keys 如 bson.D{{"email", 1}}
+func (query *Query) Index(keys bson.D) *IndexBuilder {
+	return &IndexBuilder{query: query, keys: keys, options: options.Index()}
+}
+
+// Name 指定索引名称
+func (builder *IndexBuilder) Name(name string) *IndexBuilder {
+	builder.options.SetName(name)
+	return builder
+}
+
+// Unique 唯一索引
+func (builder *IndexBuilder) Unique() *IndexBuilder {
+	builder.options.SetUnique(true)
+	return builder
+}
+
+// Sparse 稀疏索引
+func (builder *IndexBuilder) Sparse() *IndexBuilder {
+	builder.options.SetSparse(true)
+	return builder
+}
+
+// Partial 部分索引, 只索引满足 filter 的文档, 如 bson.D{{"deleted_at", bson.D{{"$exists", false}}}}
+func (builder *IndexBuilder) Partial(filter bson.D) *IndexBuilder {
+	builder.options.SetPartialFilterExpression(filter)
+	return builder
+}
+
+// Collation 指定索引的排序规则
+func (builder *IndexBuilder) Collation(collation *options.Collation) *IndexBuilder {
+	builder.options.SetCollation(collation)
+	return builder
+}
+
+// Create 创建索引
+func (builder *IndexBuilder) Create(ctx context.Context) (string, error) {
+	if builder.options.PartialFilterExpression != nil && builder.options.Sparse != nil && *builder.options.Sparse {
+		return "", errors.New("partial index can not be sparse")
+	}
+	return builder.query.createIndex(ctx, "CreateIndex", mongo.IndexModel{Keys: builder.keys, Options: builder.options})
+}
+
+// EnsureIndexes 按结构体标签创建单字段索引:
+//
+//	Email string `bson:"email" index:"unique,name=uniq_email" partial:"deleted_at:null"`
+//
+// index 可选 unique、sparse、desc、name=; partial 为 字段:值 列表, 以 ; 分隔,
+// 值 null/true/false/数字 按对应类型解析, 其余按字符串处理
+func (query *Query) EnsureIndexes(ctx context.Context, model interface{}) ([]string, error) {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.New("model must be a struct or struct pointer")
+	}
+	var names []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup("index")
+		if !ok {
+			continue
+		}
+		key := fieldKey(field)
+		if key == "-" {
+			return names, errors.New("indexed field " + field.Name + " is excluded by its bson tag")
+		}
+		var direction = 1
+		builder := query.Index(nil)
+		for _, item := range strings.Split(tag, ",") {
+			item = strings.TrimSpace(item)
+			switch {
+			case item == "unique":
+				builder.Unique()
+			case item == "sparse":
+				builder.Sparse()
+			case item == "desc":
+				direction = -1
+			case strings.HasPrefix(item, "name="):
+				builder.Name(strings.TrimPrefix(item, "name="))
+			}
+		}
+		builder.keys = bson.D{{Key: key, Value: direction}}
+		if partial, ok := field.Tag.Lookup("partial"); ok {
+			builder.Partial(parsePartialTag(partial))
+		}
+		name, err := builder.Create(ctx)
+		if err != nil {
+			return names, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// parsePartialTag 解析 partial 标签, 如 "deleted_at:null;status:1"
+func parsePartialTag(tag string) bson.D {
+	filter := bson.D{}
+	for _, item := range strings.Split(tag, ";") {
+		kv := strings.SplitN(strings.TrimSpace(item), ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		filter = append(filter, bson.E{Key: kv[0], Value: parseTagValue(kv[1])})
+	}
+	return filter
+}
+
+// parseTagValue 将标签中的字面量解析成对应类型
+func parseTagValue(s string) interface{} {
+	switch s {
+	case "null":
+		return nil
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// setIndexHidden 通过 collMod 隐藏或恢复索引
+func (query *Query) setIndexHidden(ctx context.Context, method, name string, hidden bool) error {
+	return query.run(ctx, method, func(ctx context.Context) error {
+		return query.Database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: query.Table.Name()},
+			{Key: "index", Value: bson.D{{Key: "name", Value: name}, {Key: "hidden", Value: hidden}}},
+		}).Err()
+	})
+}
+
+// HideIndex 隐藏索引, 查询规划不再使用, 但仍然维护, 用于删除索引前验证影响
+func (query *Query) HideIndex(ctx context.Context, name string) error {
+	return query.setIndexHidden(ctx, "HideIndex", name, true)
+}
+
+// UnhideIndex 恢复被隐藏的索引
+func (query *Query) UnhideIndex(ctx context.Context, name string) error {
+	return query.setIndexHidden(ctx, "UnhideIndex", name, false)
+}
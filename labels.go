@@ -0,0 +1,27 @@
+package mongodb
+
+import (
+	"sort"
+	"strings"
+)
+
+// Labels 连接的标签(Opt.Labels), 不要修改返回的 map
+func (client *MongoDBClient) Labels() map[string]string {
+	if client.opt == nil {
+		return nil
+	}
+	return client.opt.Labels
+}
+
+// labelSuffix 日志中的标签, 按名称排序, 没有标签时返回空字符串
+func labelSuffix(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return " labels:" + strings.Join(pairs, ",")
+}
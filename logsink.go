@@ -0,0 +1,191 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LogEntry 写入固定集合的一条日志
+type LogEntry struct {
+	Time    time.Time `bson:"time"`
+	Level   string    `bson:"level"`
+	Message string    `bson:"message"`
+}
+
+// CappedLoggerOptions CappedLogger 参数
+type CappedLoggerOptions struct {
+	// SizeBytes 固定集合大小, 默认 64MB, 集合已存在时不生效
+	SizeBytes int64
+	// MaxDocs 固定集合最多文档数, 0 不限制
+	MaxDocs int64
+	// BatchSize 每批写入条数, 默认 100
+	BatchSize int
+	// FlushInterval 最长写入间隔, 默认 1 秒
+	FlushInterval time.Duration
+	// Buffer 待写入队列长度, 默认 1000, 队列满时丢弃新日志
+	Buffer int
+}
+
+// CappedLogger 将日志异步批量写入固定集合的 Logger 实现
+type CappedLogger struct {
+	collection *mongo.Collection
+	opts       CappedLoggerOptions
+	entries    chan LogEntry
+	flush      chan chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+
+	mu      sync.Mutex
+	closed  bool
+	dropped int64
+}
+
+// NewCappedLogger 创建写入 collection 的 Logger, 集合不存在时创建为固定集合
+func NewCappedLogger(client *MongoDBClient, collection string, opts CappedLoggerOptions) (*CappedLogger, error) {
+	if opts.SizeBytes <= 0 {
+		opts.SizeBytes = 64 * 1024 * 1024
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 1000
+	}
+	database := client.Client.Database(client.database())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	createOptions := options.CreateCollection().SetCapped(true).SetSizeInBytes(opts.SizeBytes)
+	if opts.MaxDocs > 0 {
+		createOptions.SetMaxDocuments(opts.MaxDocs)
+	}
+	err := database.CreateCollection(ctx, collection, createOptions)
+	var commandErr mongo.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && commandErr.Name == "NamespaceExists") {
+		return nil, err
+	}
+	logger := &CappedLogger{
+		collection: database.Collection(collection),
+		opts:       opts,
+		entries:    make(chan LogEntry, opts.Buffer),
+		flush:      make(chan chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go logger.loop()
+	return logger, nil
+}
+
+// loop 攒批写入, 直接使用驱动写入, 避免慢查询日志等再次进入本 Logger
+func (logger *CappedLogger) loop() {
+	defer close(logger.done)
+	ticker := time.NewTicker(logger.opts.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]interface{}, 0, logger.opts.BatchSize)
+	write := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := logger.collection.InsertMany(ctx, batch, options.InsertMany().SetOrdered(false))
+		cancel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "mongodb: write log entries failed:", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case entry, ok := <-logger.entries:
+			if !ok {
+				write()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= logger.opts.BatchSize {
+				write()
+			}
+		case <-ticker.C:
+			write()
+		case ack := <-logger.flush:
+			for n := len(logger.entries); n > 0; n-- {
+				batch = append(batch, <-logger.entries)
+			}
+			write()
+			close(ack)
+		}
+	}
+}
+
+// Dropped 因队列已满丢弃的日志条数
+func (logger *CappedLogger) Dropped() int64 {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return logger.dropped
+}
+
+// Flush 立即写入队列中的日志
+func (logger *CappedLogger) Flush() {
+	ack := make(chan struct{})
+	select {
+	case logger.flush <- ack:
+		<-ack
+	case <-logger.done:
+	}
+}
+
+// Close 写入剩余日志并停止, 之后的日志被丢弃
+func (logger *CappedLogger) Close() {
+	logger.closeOnce.Do(func() {
+		logger.Flush()
+		logger.mu.Lock()
+		logger.closed = true
+		close(logger.entries)
+		logger.mu.Unlock()
+		<-logger.done
+	})
+}
+
+func (logger *CappedLogger) log(level string, args ...interface{}) {
+	entry := LogEntry{Time: time.Now(), Level: level, Message: fmt.Sprint(args...)}
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	if logger.closed {
+		logger.dropped++
+		return
+	}
+	select {
+	case logger.entries <- entry:
+	default:
+		logger.dropped++
+	}
+}
+
+// Panic 写入日志后 panic
+func (logger *CappedLogger) Panic(args ...interface{}) {
+	logger.log("panic", args...)
+	logger.Flush()
+	panic(fmt.Sprint(args...))
+}
+
+// Fatal 写入日志后退出进程
+func (logger *CappedLogger) Fatal(args ...interface{}) {
+	logger.log("fatal", args...)
+	logger.Close()
+	os.Exit(1)
+}
+
+func (logger *CappedLogger) Error(args ...interface{})   { logger.log("error", args...) }
+func (logger *CappedLogger) Warning(args ...interface{}) { logger.log("warning", args...) }
+func (logger *CappedLogger) Warn(args ...interface{})    { logger.log("warning", args...) }
+func (logger *CappedLogger) Info(args ...interface{})    { logger.log("info", args...) }
+func (logger *CappedLogger) Debug(args ...interface{})   { logger.log("debug", args...) }
+func (logger *CappedLogger) Trace(args ...interface{})   { logger.log("trace", args...) }
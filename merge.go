@@ -0,0 +1,107 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// $merge 的 whenMatched / whenNotMatched 取值
+const (
+	MergeReplace      = "replace"
+	MergeKeepExisting = "keepExisting"
+	MergeMerge        = "merge"
+	MergeFail         = "fail"
+	MergeInsert       = "insert"
+	MergeDiscard      = "discard"
+)
+
+// MergeOptions $merge 阶段参数
+type MergeOptions struct {
+	// Database 目标数据库, 为空时与源集合相同
+	Database string
+	// On 匹配字段, 默认 _id, 其他字段需要有唯一索引
+	On []string
+	// WhenMatched 目标已存在时的处理, 默认 merge; 设置了 WhenMatchedPipeline 时忽略
+	WhenMatched string
+	// WhenMatchedPipeline 目标已存在时用于更新的管道, 可以通过 $$new 引用新文档
+	WhenMatchedPipeline bson.A
+	// Let 在 WhenMatchedPipeline 中可用的变量
+	Let bson.D
+	// WhenNotMatched 目标不存在时的处理, 默认 insert
+	WhenNotMatched string
+}
+
+// MergeStage 构造 $merge 阶段, 把聚合结果合并到 into 集合
+func MergeStage(into string, opts *MergeOptions) bson.D {
+	if opts == nil {
+		opts = &MergeOptions{}
+	}
+	var target interface{} = into
+	if opts.Database != "" {
+		target = bson.D{{Key: "db", Value: opts.Database}, {Key: "coll", Value: into}}
+	}
+	merge := bson.D{{Key: "into", Value: target}}
+	switch len(opts.On) {
+	case 0:
+	case 1:
+		merge = append(merge, bson.E{Key: "on", Value: opts.On[0]})
+	default:
+		merge = append(merge, bson.E{Key: "on", Value: opts.On})
+	}
+	if len(opts.Let) > 0 {
+		merge = append(merge, bson.E{Key: "let", Value: opts.Let})
+	}
+	if len(opts.WhenMatchedPipeline) > 0 {
+		merge = append(merge, bson.E{Key: "whenMatched", Value: opts.WhenMatchedPipeline})
+	} else if opts.WhenMatched != "" {
+		merge = append(merge, bson.E{Key: "whenMatched", Value: opts.WhenMatched})
+	}
+	if opts.WhenNotMatched != "" {
+		merge = append(merge, bson.E{Key: "whenNotMatched", Value: opts.WhenNotMatched})
+	}
+	return bson.D{{Key: "$merge", Value: merge}}
+}
+
+// OutStage 构造 $out 阶段, 用聚合结果整体替换 collection
+func OutStage(collection string) bson.D {
+	return bson.D{{Key: "$out", Value: collection}}
+}
+
+// OutStageTo 构造输出到其他数据库的 $out 阶段
+func OutStageTo(database, collection string) bson.D {
+	return bson.D{{Key: "$out", Value: bson.D{{Key: "db", Value: database}, {Key: "coll", Value: collection}}}}
+}
+
+// MaterializeInto 以链式条件过滤源集合, 依次执行 stages, 再按 _id 把结果合并(替换已存在的文档)到 target,
+// 用于维护物化视图. 该操作不附加操作超时, 由 ctx 控制
+func (query *Query) MaterializeInto(ctx context.Context, target string, stages ...bson.D) error {
+	return query.MaterializeWith(ctx, MergeStage(target, &MergeOptions{WhenMatched: MergeReplace, WhenNotMatched: MergeInsert}), stages...)
+}
+
+// MaterializeWith 与 MaterializeInto 相同, 使用自定义的 $merge / $out 阶段 output
+func (query *Query) MaterializeWith(ctx context.Context, output bson.D, stages ...bson.D) error {
+	pipeline := make(bson.A, 0, len(stages)+2)
+	if len(query.filter) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: query.filter}})
+	}
+	for _, stage := range stages {
+		pipeline = append(pipeline, stage)
+	}
+	pipeline = append(pipeline, output)
+	return query.run(ctx, "Materialize", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{
+			Collation: query.collation,
+			Comment:   commentOf(ctx),
+		})
+		if err != nil {
+			return err
+		}
+		return cursor.Close(ctx)
+	})
+}
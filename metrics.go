@@ -0,0 +1,141 @@
+package mongodb
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// PoolEventKind 连接池事件类型
+type PoolEventKind int
+
+const (
+	// PoolConnectionOpened 新建连接
+	PoolConnectionOpened PoolEventKind = iota
+	// PoolConnectionClosed 关闭连接
+	PoolConnectionClosed
+	// PoolCheckedOut 获取连接成功, 附带等待时间
+	PoolCheckedOut
+	// PoolCheckedIn 归还连接
+	PoolCheckedIn
+	// PoolCheckOutFailed 获取连接失败, 附带等待时间
+	PoolCheckOutFailed
+)
+
+// OperationMetric 一次操作的指标
+type OperationMetric struct {
+	Connection string
+	Collection string
+	Method     string
+	// Labels 连接的标签(Opt.Labels)
+	Labels  map[string]string
+	Latency time.Duration
+	// Err 操作的错误, 查询不到文档时为 nil
+	Err error
+}
+
+// PoolMetric 一次连接池事件
+type PoolMetric struct {
+	Connection string
+	Labels     map[string]string
+	Kind       PoolEventKind
+	// Wait 获取连接的等待时间, 只在 PoolCheckedOut 和 PoolCheckOutFailed 时有值
+	Wait time.Duration
+}
+
+// DecodeMetric 一次文档解码失败
+type DecodeMetric struct {
+	Connection string
+	Collection string
+	Method     string
+	Labels     map[string]string
+	Err        *DecodeError
+}
+
+// DecodeMetricsCollector 可选实现, 采集后端同时实现该接口时接收解码失败事件.
+// 解码失败同样作为操作错误交给 ObserveOperation, 游标逐条解码和缓存命中时的失败则只通过该接口上报
+type DecodeMetricsCollector interface {
+	ObserveDecode(metric DecodeMetric)
+}
+
+// MetricsCollector 指标采集后端, 通过 Configs.SetMetricsCollector 设置, 内置 Prometheus 实现(NewPrometheusCollector).
+// 方法在操作和驱动的事件回调中同步调用, 实现需要并发安全且不能阻塞
+type MetricsCollector interface {
+	ObserveOperation(metric OperationMetric)
+	ObservePool(metric PoolMetric)
+}
+
+// metricsHolder atomic.Value 要求存入相同的具体类型
+type metricsHolder struct {
+	collector MetricsCollector
+}
+
+// SetMetricsCollector 为所有连接(包括已创建的连接)设置指标采集, 传入 nil 关闭
+func (configs *Configs) SetMetricsCollector(collector MetricsCollector) {
+	configs.mu.Lock()
+	defer configs.mu.Unlock()
+	configs.metrics = collector
+	for _, conn := range configs.connections {
+		conn.setMetrics(collector)
+	}
+}
+
+func (client *MongoDBClient) setMetrics(collector MetricsCollector) {
+	if client.metrics != nil {
+		client.metrics.Store(metricsHolder{collector: collector})
+	}
+}
+
+// metricsCollector 连接使用的指标采集后端, 没有设置时为 nil
+func (client *MongoDBClient) metricsCollector() MetricsCollector {
+	return loadMetrics(client.metrics)
+}
+
+func loadMetrics(value *atomic.Value) MetricsCollector {
+	if value == nil {
+		return nil
+	}
+	holder, _ := value.Load().(metricsHolder)
+	return holder.collector
+}
+
+// observeOperation 把操作结果交给指标采集后端
+func (query *Query) observeOperation(method string, latency time.Duration, err error) {
+	collector := query.client.metricsCollector()
+	if collector == nil {
+		return
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = nil
+	}
+	collector.ObserveOperation(OperationMetric{
+		Connection: query.client.ConnectionName,
+		Collection: query.namespace(),
+		Method:     method,
+		Labels:     query.client.Labels(),
+		Latency:    latency,
+		Err:        err,
+	})
+}
+
+// observeDecode 记录解码失败, err 不是 DecodeError 时忽略
+func (query *Query) observeDecode(method string, err error) {
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		return
+	}
+	query.client.stats.recordDecode(query.namespace(), method)
+	collector, ok := query.client.metricsCollector().(DecodeMetricsCollector)
+	if !ok {
+		return
+	}
+	collector.ObserveDecode(DecodeMetric{
+		Connection: query.client.ConnectionName,
+		Collection: query.namespace(),
+		Method:     method,
+		Labels:     query.client.Labels(),
+		Err:        decodeErr,
+	})
+}
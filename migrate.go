@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ReadMigration 读取时迁移, 在解码前修改旧文档(补默认值、改字段名等), 返回文档是否被修改.
+// 只影响解码结果, 不会写回数据库
+type ReadMigration func(doc bson.M) bool
+
+// AddReadMigration 为集合添加读取时迁移, 按添加顺序执行, 结构演进不需要先全量回填数据
+func (collection *Collection) AddReadMigration(migrations ...ReadMigration) {
+	collection.mu.Lock()
+	collection.migrations = append(collection.migrations, migrations...)
+	collection.mu.Unlock()
+}
+
+// SetReadDefaults 读取时为缺少的顶层字段补上默认值, 字段为 null 时保持不变
+func (collection *Collection) SetReadDefaults(defaults bson.M) {
+	collection.AddReadMigration(func(doc bson.M) bool {
+		changed := false
+		for field, value := range defaults {
+			if _, ok := doc[field]; !ok {
+				doc[field] = value
+				changed = true
+			}
+		}
+		return changed
+	})
+}
+
+// RenameOnRead 读取时把旧字段名(key)改为新字段名(value), 文档中已有新字段时保留新字段
+func (collection *Collection) RenameOnRead(renames map[string]string) {
+	collection.AddReadMigration(func(doc bson.M) bool {
+		changed := false
+		for from, to := range renames {
+			value, ok := doc[from]
+			if !ok {
+				continue
+			}
+			if _, exists := doc[to]; !exists {
+				doc[to] = value
+			}
+			delete(doc, from)
+			changed = true
+		}
+		return changed
+	})
+}
+
+// migrate 执行读取时迁移, 没有迁移或文档无法解析时原样返回(解析错误由解码返回)
+func (collection *Collection) migrate(raw bson.Raw) bson.Raw {
+	collection.mu.RLock()
+	migrations := collection.migrations
+	collection.mu.RUnlock()
+	if len(migrations) == 0 {
+		return raw
+	}
+	doc := bson.M{}
+	if err := unmarshal(raw, &doc); err != nil {
+		return raw
+	}
+	changed := false
+	for _, migration := range migrations {
+		if migration(doc) {
+			changed = true
+		}
+	}
+	if !changed {
+		return raw
+	}
+	data, err := marshal(doc)
+	if err != nil {
+		return raw
+	}
+	return data
+}
@@ -3,14 +3,19 @@ package mongodb
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
 var Log Logger
@@ -32,19 +37,84 @@ type Logger interface {
 
 type MongoDBClient struct {
 	Client *mongo.Client
-	Name   string
-}
+	// Name 默认数据库名, 保留用于兼容, 新代码请使用 DefaultDatabase
+	Name string
+	// ConnectionName Configs 中的连接名
+	ConnectionName string
+	// DefaultDatabase Collection 使用的数据库
+	DefaultDatabase string
+
+	maintenance int32
+	collections sync.Map
+	prepared    sync.Map
+	stats       *statsRegistry
+	advisor     *indexAdvisor
+	slowLog     *slowLog
+	pool        *poolCounter
+	opt         *Opt
+	chaos       atomic.Value
+	tracer      atomic.Value
+	health      atomic.Value
+	metrics     *atomic.Value
+
+	inflightMu sync.Mutex
+	inflight   int
+	shutting   bool
+	drained    chan struct{}
+	// closing Shutdown 开始时关闭, 取消变更流等长期运行的操作
+	closing chan struct{}
+}
+
+// ErrMaintenance 连接处于维护模式, 操作被直接拒绝
+var ErrMaintenance = errors.New("mongodb: connection is in maintenance mode")
 
 // var client *mongo.Client
 
-type collection struct {
+// Collection 集合句柄, 按集合名缓存在连接上, 创建后不再修改, 可以在多个请求间复用
+type Collection struct {
+	client   *MongoDBClient
 	Database *mongo.Database
 	Table    *mongo.Collection
-	filter   bson.D
-	limit    int64
-	skip     int64
-	sort     bson.D
-	fields   bson.M
+
+	mu            sync.RWMutex
+	decodeHook    DecodeHook
+	migrations    []ReadMigration
+	cache         *resultCache
+	defaultFilter bson.D
+	defaultSort   bson.D
+	shardKey      []string
+	indexPatterns [][]string
+	allowScan     bool
+}
+
+// Query 单次查询的条件, 链式方法返回新的 Query, 不会修改调用者持有的对象
+type Query struct {
+	*Collection
+	// Table 执行操作的集合, 设置了 ReadPref / ReadConcern / WriteConcern 时为带这些选项的副本
+	Table     *mongo.Collection
+	filter    bson.D
+	limit     int64
+	skip      int64
+	sort      bson.D
+	fields    bson.M
+	collation *options.Collation
+	ctx       context.Context
+	session   mongo.Session
+	// where 链式方法设置的条件, filter 为合并默认条件后实际使用的条件
+	where    bson.D
+	scope    bson.D
+	unscoped bool
+	// timeout Timeout 指定的单次操作超时
+	timeout time.Duration
+	// expireAt ExpiresIn / ExpiresAt 指定的写入文档过期时间
+	expireAt func() time.Time
+	// hint、let Hint / Let 指定的索引和聚合变量
+	hint interface{}
+	let  interface{}
+	// minBound、maxBound、returnKey MinBound / MaxBound / ReturnKey 指定的索引范围
+	minBound  interface{}
+	maxBound  interface{}
+	returnKey bool
 }
 
 //Config .
@@ -54,13 +124,71 @@ type Opt struct {
 	MaxPoolSize     int
 	MinPoolSize     int
 	Database        string
+	// DirectConnection 直连单个节点, 不做副本集发现(隐藏从节点、本地测试实例等)
+	DirectConnection bool
+	// SRVMaxHosts mongodb+srv 模式下最多连接的主机数, 0 不限制
+	SRVMaxHosts int
+	// SRVServiceName mongodb+srv 模式下的 SRV 服务名, 默认 mongodb
+	SRVServiceName string
+	// OnHostsChange SRV 发现的主机列表变化时回调, 回调期间拓扑被锁定, 不要在里面执行数据库操作
+	OnHostsChange func(hosts []string)
+	// LoadBalanced 通过负载均衡器连接(mongos 负载均衡、Atlas serverless)
+	LoadBalanced bool
+	// OnTopologyEvent 拓扑变化(节点增减、主节点切换)时回调, 回调期间拓扑被锁定, 不要在里面执行数据库操作
+	OnTopologyEvent func(TopologyEvent)
+	// PprofLabels 为每个操作打上 pprof 标签(mongodb.collection、mongodb.method), 便于在 CPU/goroutine profile 中定位
+	PprofLabels bool
+	// SlowQueryThreshold 慢查询阈值, 超过时记录警告日志, 0 不记录
+	SlowQueryThreshold time.Duration
+	// ExplainSlowQueries 慢查询时在后台执行 explain("executionStats") 记录执行计划
+	ExplainSlowQueries bool
+	// OnSlowQuery 慢查询回调, 开启 ExplainSlowQueries 时在拿到执行计划后回调
+	OnSlowQuery func(SlowQuery)
+	// IndexAdvisor 记录查询形状并分析执行计划, 通过 Configs.IndexAdvice 获取索引建议
+	IndexAdvisor bool
+	// DocumentSizeGuard 写入前测量文档的 BSON 大小, 超过 16MB 直接返回 DocumentTooLargeError
+	DocumentSizeGuard bool
+	// DocumentSizeWarn 开启 DocumentSizeGuard 时, 文档超过该字节数记录警告日志, 0 不警告
+	DocumentSizeWarn int
+	// Tracer 链路追踪后端, 每个操作创建一个 span, 使用 OpenTelemetry 时可以用 NewOTelTracer 或 Configs.SetTracerProvider
+	Tracer Tracer
+	// RetryDuplicateID 本包生成的 _id 写入时重复, 换一个 _id 重试的最大次数, 0 不重试
+	RetryDuplicateID int
+	// ReadPolicy 按调用方角色(WithRole)限制可读取的字段和文档
+	ReadPolicy ReadPolicy
+	// OperationTimeout 单次操作的超时, 0 使用默认的 5 秒
+	OperationTimeout time.Duration
+	// Chaos 故障注入规则, 只用于测试环境, 运行时可以通过 SetChaos 修改
+	Chaos []ChaosRule
+	// QueryGuard 查询守卫, 条件不匹配 DeclareIndex 声明的索引时告警或拒绝, 防止全表扫描进入生产环境
+	QueryGuard GuardMode
+	// ReadPreference 读偏好, 如 readpref.SecondaryPreferred(), 可以用 Query.ReadPref 覆盖
+	ReadPreference *readpref.ReadPref
+	// ReadConcern 读关注, 如 readconcern.Majority(), 可以用 Query.ReadConcern 覆盖
+	ReadConcern *readconcern.ReadConcern
+	// WriteConcern 写关注, 如 writeconcern.New(writeconcern.WMajority()), 可以用 Query.WriteConcern 覆盖
+	WriteConcern *writeconcern.WriteConcern
+	// Labels 连接的标签(如 team、service、environment), 附加到指标、span 和日志中, 便于按团队统计共享集群的负载
+	Labels map[string]string
+	// ConnectRetries 建立连接失败后的重试次数, 0 不重试
+	ConnectRetries int
+	// ConnectBackoff 第一次重试前的等待时间, 之后每次翻倍, 默认 1 秒
+	ConnectBackoff time.Duration
 }
 
 // Configs 配置
 type Configs struct {
 	opt         map[string]*Opt
 	connections map[string]*MongoDBClient
-	mu          sync.RWMutex
+	maintenance map[string]bool
+	stats       *statsRegistry
+	advisor     *indexAdvisor
+	slowLog     *slowLog
+	tracer      Tracer
+	metrics     MetricsCollector
+	// connectErrors 建立失败、还没有成功建立的连接的最近一次错误
+	connectErrors map[string]error
+	mu            sync.RWMutex
 }
 
 //Default ..
@@ -68,6 +196,10 @@ func Default() *Configs {
 	return &Configs{
 		opt:         make(map[string]*Opt),
 		connections: make(map[string]*MongoDBClient),
+		maintenance: make(map[string]bool),
+		stats:       newStatsRegistry(),
+		advisor:     newIndexAdvisor(),
+		slowLog:     &slowLog{},
 	}
 }
 
@@ -77,300 +209,593 @@ func (configs *Configs) SetOpt(name string, cf *Opt) *Configs {
 	return configs
 }
 
-//connect 数据库连接
-func connect(config *Opt, name string) *MongoDBClient {
+//connect 数据库连接, ping 为 true 时确认服务器可达
+func connect(config *Opt, name string, collector MetricsCollector, ping bool) (*MongoDBClient, error) {
+	if err := validateSRV(config); err != nil {
+		return nil, err
+	}
 	//数据库连接
 	mongoOptions := options.Client()
+	mongoOptions.SetRegistry(registry)
 	mongoOptions.SetMaxConnIdleTime(time.Duration(config.MaxConnIdleTime) * time.Second)
 	mongoOptions.SetMaxPoolSize(uint64(config.MaxPoolSize))
 	mongoOptions.SetMinPoolSize(uint64(config.MinPoolSize))
-	client, err := mongo.NewClient(mongoOptions.ApplyURI(config.Url))
+	if config.DirectConnection {
+		mongoOptions.SetDirect(true)
+	}
+	if config.LoadBalanced {
+		mongoOptions.SetLoadBalanced(true)
+	}
+	if isSRV(config.Url) {
+		if config.SRVMaxHosts > 0 {
+			mongoOptions.SetSRVMaxHosts(config.SRVMaxHosts)
+		}
+		if config.SRVServiceName != "" {
+			mongoOptions.SetSRVServiceName(config.SRVServiceName)
+		}
+	}
+	if monitor := serverMonitor(name, config); monitor != nil {
+		mongoOptions.SetServerMonitor(monitor)
+	}
+	// 在建立连接前设置, 不漏掉初始连接的事件
+	metrics := &atomic.Value{}
+	metrics.Store(metricsHolder{collector: collector})
+	pool := &poolCounter{connection: name, labels: config.Labels, metrics: metrics}
+	mongoOptions.SetPoolMonitor(pool.monitor())
+	mongoOptions.ApplyURI(config.Url)
+	// 显式配置优先于连接串中的同名参数
+	if config.ReadPreference != nil {
+		mongoOptions.SetReadPreference(config.ReadPreference)
+	}
+	if config.ReadConcern != nil {
+		mongoOptions.SetReadConcern(config.ReadConcern)
+	}
+	if config.WriteConcern != nil {
+		mongoOptions.SetWriteConcern(config.WriteConcern)
+	}
+	client, err := mongo.NewClient(mongoOptions)
 	if err != nil {
-		Log.Panic(err)
-		return nil
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = client.Connect(ctx); err != nil {
+		return nil, err
+	}
+	if ping {
+		if err = client.Ping(ctx, nil); err != nil {
+			_ = client.Disconnect(context.Background())
+			return nil, err
+		}
+	}
+	return &MongoDBClient{
+		Client:          client,
+		Name:            config.Database,
+		ConnectionName:  name,
+		DefaultDatabase: config.Database,
+		opt:             config,
+		pool:            pool,
+		metrics:         metrics,
+		closing:         make(chan struct{}),
+	}, nil
+}
+
+// withRetry 按 Opt.ConnectRetries 重试建立连接, 每次失败后等待的时间翻倍
+func withRetry(config *Opt, name string, fn func() error) error {
+	backoff := config.ConnectBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= config.ConnectRetries {
+			return err
+		}
+		if Log != nil {
+			Log.Warn("MongoDB连接失败, ", backoff, "后重试->", name, " ", err, labelSuffix(config.Labels))
+		}
+		time.Sleep(backoff)
+		backoff *= 2
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	err = client.Connect(ctx)
+}
+
+//GetMongoDB 获取实列, 连接失败时 Log.Panic, 需要处理错误时使用 GetMongoDBE
+func (configs *Configs) GetMongoDB(name string) *MongoDBClient {
+	db, err := configs.GetMongoDBE(name)
 	if err != nil {
 		Log.Panic("MongoDB连接失败->", err)
 		return nil
 	}
-	return &MongoDBClient{Client: client, Name: name}
+	return db
 }
 
-//GetMongoDB 获取实列
-func (configs *Configs) GetMongoDB(name string) *MongoDBClient {
+// GetMongoDBE 获取实列, 与 GetMongoDB 相同但失败时返回错误; 与驱动一样不等待服务器可达
+func (configs *Configs) GetMongoDBE(name string) (*MongoDBClient, error) {
+	return configs.getMongoDB(name, false)
+}
+
+// Connect 获取实列并确认服务器可达(ping), 失败时按 Opt.ConnectRetries 重试, 用于启动时检查连接
+func (configs *Configs) Connect(name string) (*MongoDBClient, error) {
+	return configs.getMongoDB(name, true)
+}
+
+func (configs *Configs) getMongoDB(name string, ping bool) (*MongoDBClient, error) {
+	configs.mu.RLock()
 	conn, ok := configs.connections[name]
+	config, found := configs.opt[name]
+	collector := configs.metrics
+	configs.mu.RUnlock()
 	if ok {
-		return conn
+		if !ping {
+			return conn, nil
+		}
+		err := withRetry(conn.opt, name, func() error {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			return conn.Client.Ping(ctx, nil)
+		})
+		return conn, err
 	}
-	config, ok := configs.opt[name]
-	if !ok {
-		Log.Panic("MongoDB配置:" + name + "找不到！")
+	if !found {
+		return nil, fmt.Errorf("mongodb config %q not found", name)
 	}
-	db := connect(config, config.Database)
+	var db *MongoDBClient
+	err := withRetry(config, name, func() (err error) {
+		db, err = connect(config, name, collector, ping)
+		return
+	})
+	if err != nil {
+		configs.connectFailed(name, err)
+		return nil, err
+	}
+	return configs.register(name, db), nil
+}
+
+// connectFailed 记录连接建立失败, 用于 Status
+func (configs *Configs) connectFailed(name string, err error) {
+	configs.mu.Lock()
+	defer configs.mu.Unlock()
+	if configs.connectErrors == nil {
+		configs.connectErrors = make(map[string]error)
+	}
+	configs.connectErrors[name] = err
+}
+
+// register 保存新建立的连接, 并发获取时保留先建立的连接
+func (configs *Configs) register(name string, db *MongoDBClient) *MongoDBClient {
 	configs.mu.Lock()
+	defer configs.mu.Unlock()
+	if conn, ok := configs.connections[name]; ok {
+		_ = db.Client.Disconnect(context.Background())
+		return conn
+	}
+	delete(configs.connectErrors, name)
+	if configs.maintenance[name] {
+		db.maintenance = 1
+	}
+	db.stats = configs.stats
+	db.advisor = configs.advisor
+	db.slowLog = configs.slowLog
+	db.tracer.Store(tracerHolder{tracer: configs.tracer})
 	configs.connections[name] = db
-	configs.mu.Unlock()
+	return db
+}
 
-	configs.mu.RLock()
-	v := configs.connections[name]
-	configs.mu.RUnlock()
-	return v
+// SetMaintenance 设置维护模式, 开启后该连接上的操作直接返回 ErrMaintenance
+func (configs *Configs) SetMaintenance(name string, on bool) {
+	configs.mu.Lock()
+	defer configs.mu.Unlock()
+	configs.maintenance[name] = on
+	if conn, ok := configs.connections[name]; ok {
+		conn.setMaintenance(on)
+	}
+}
 
+func (client *MongoDBClient) setMaintenance(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+	atomic.StoreInt32(&client.maintenance, v)
 }
 
-func (collection *collection) reset() {
-	collection.filter = nil
-	collection.limit = 0
-	collection.skip = 0
-	collection.sort = nil
-	collection.fields = nil
-	collection.Table = nil
+// InMaintenance 是否处于维护模式
+func (client *MongoDBClient) InMaintenance() bool {
+	return atomic.LoadInt32(&client.maintenance) == 1
 }
 
-// Collection 得到一个mongo操作对象
-func (client *MongoDBClient) Collection(table string) *collection {
-	database := client.Client.Database(client.Name)
-	return &collection{
+// handle 获取缓存的集合句柄, 不存在时创建
+func (client *MongoDBClient) handle(table string) *Collection {
+	if v, ok := client.collections.Load(table); ok {
+		return v.(*Collection)
+	}
+	database := client.Client.Database(client.database())
+	v, _ := client.collections.LoadOrStore(table, &Collection{
+		client:   client,
 		Database: database,
 		Table:    database.Collection(table),
-		filter:   make(bson.D, 0),
-		sort:     make(bson.D, 0),
+	})
+	return v.(*Collection)
+}
+
+// database 默认数据库名, 兼容只设置了 Name 的连接
+func (client *MongoDBClient) database() string {
+	if client.DefaultDatabase != "" {
+		return client.DefaultDatabase
 	}
+	return client.Name
+}
+
+// Collection 得到一个mongo操作对象
+func (client *MongoDBClient) Collection(table string) *Query {
+	collection := client.handle(table)
+	collection.mu.RLock()
+	query := &Query{Collection: collection, Table: collection.Table, where: bson.D{}, scope: collection.defaultFilter, sort: collection.defaultSort}
+	collection.mu.RUnlock()
+	query.applyScope()
+	return query
+}
+
+// clone 复制查询条件, 链式方法在副本上修改
+func (query *Query) clone() *Query {
+	q := *query
+	return &q
 }
 
 // 条件查询, bson.M{"field": "value"}
-func (collection *collection) Where(m bson.D) *collection {
-	collection.filter = m
-	return collection
+func (query *Query) Where(m bson.D) *Query {
+	query = query.clone()
+	query.where = m
+	query.applyScope()
+	return query
+}
+
+// WhereID 按 _id 查询, 接受 ObjectID、十六进制字符串、UUID 或它们的切片, 切片时为 $in 查询
+func (query *Query) WhereID(id interface{}) *Query {
+	if !isIDList(id) {
+		return query.and(bson.E{Key: "_id", Value: normalizeID(id)})
+	}
+	return query.and(bson.E{Key: "_id", Value: bson.D{{Key: "$in", Value: normalizeIDs(id)}}})
+}
+
+// and 在现有条件上追加一个条件
+func (query *Query) and(e bson.E) *Query {
+	query = query.clone()
+	where := make(bson.D, 0, len(query.where)+1)
+	query.where = append(append(where, query.where...), e)
+	query.applyScope()
+	return query
 }
 
 // 限制条数
-func (collection *collection) Limit(n int64) *collection {
-	collection.limit = n
-	return collection
+func (query *Query) Limit(n int64) *Query {
+	query = query.clone()
+	query.limit = n
+	return query
 }
 
 // 跳过条数
-func (collection *collection) Skip(n int64) *collection {
-	collection.skip = n
-	return collection
+func (query *Query) Skip(n int64) *Query {
+	query = query.clone()
+	query.skip = n
+	return query
 }
 
 // 排序 bson.M{"created_at":-1}
-func (collection *collection) Sort(sorts bson.D) *collection {
-	collection.sort = sorts
-	return collection
+func (query *Query) Sort(sorts bson.D) *Query {
+	query = query.clone()
+	query.sort = sorts
+	return query
 }
 
 // 指定查询字段
-func (collection *collection) Fields(fields bson.M) *collection {
-	collection.fields = fields
-	return collection
+func (query *Query) Fields(fields bson.M) *Query {
+	query = query.clone()
+	query.fields = fields
+	return query
+}
+
+// Collation 指定排序规则, 查询、计数、更新、删除都会使用
+func (query *Query) Collation(collation *options.Collation) *Query {
+	query = query.clone()
+	query.collation = collation
+	return query
+}
+
+// findOptions 链式条件对应的 Find 参数
+func (query *Query) findOptions(ctx context.Context) *options.FindOptions {
+	return &options.FindOptions{
+		Comment:    commentOf(ctx),
+		Skip:       &query.skip,
+		Limit:      &query.limit,
+		Sort:       query.sort,
+		Projection: query.projection(ctx),
+		Collation:  query.collation,
+		Hint:       query.hint,
+		Let:        query.let,
+		Min:        query.minBound,
+		Max:        query.maxBound,
+		ReturnKey:  query.returnKeyOption(),
+	}
+}
+
+// findOneOptions 链式条件对应的 FindOne 参数
+func (query *Query) findOneOptions(ctx context.Context) *options.FindOneOptions {
+	return &options.FindOneOptions{
+		Comment:    commentOf(ctx),
+		Skip:       &query.skip,
+		Sort:       query.sort,
+		Projection: query.projection(ctx),
+		Collation:  query.collation,
+		Hint:       query.hint,
+		Min:        query.minBound,
+		Max:        query.maxBound,
+		ReturnKey:  query.returnKeyOption(),
+	}
+}
+
+// returnKeyOption 没有调用 ReturnKey 时不发送该参数
+func (query *Query) returnKeyOption() *bool {
+	if !query.returnKey {
+		return nil
+	}
+	return &query.returnKey
+}
+
+// CaseInsensitive 使用不区分大小写的排序规则, 与 CreateCaseInsensitiveIndex 创建的索引匹配
+func (query *Query) CaseInsensitive() *Query {
+	return query.Collation(CaseInsensitiveCollation)
 }
 
 //CreateOneIndex 创建单个普通索引
-func (collection *collection) CreateIndex(key bson.D, op *options.IndexOptions) (res string, err error) {
-	ctx := context.Background()
-	indexView := collection.Table.Indexes()
-	indexModel := mongo.IndexModel{Keys: key, Options: op}
-	res, err = indexView.CreateOne(ctx, indexModel)
+func (query *Query) CreateIndex(key bson.D, op *options.IndexOptions) (res string, err error) {
+	err = query.run(query.baseContext(), "CreateIndex", func(ctx context.Context) (err error) {
+		indexModel := mongo.IndexModel{Keys: key, Options: op}
+		res, err = query.Table.Indexes().CreateOne(ctx, indexModel)
+		return
+	})
 	return
 }
 
 //ListIndexes 获取所有所有
-func (collection *collection) ListIndexes(opts *options.ListIndexesOptions) (interface{}, error) {
-	ctx := context.Background()
+func (query *Query) ListIndexes(opts *options.ListIndexesOptions) (interface{}, error) {
 	var results interface{}
-	indexView := collection.Table.Indexes()
-	cursor, err := indexView.List(ctx, opts)
-	if err != nil {
-		collection.reset()
-		return nil, err
-	}
-
-	err = cursor.All(ctx, &results)
+	err := query.run(query.baseContext(), "ListIndexes", func(ctx context.Context) error {
+		cursor, err := query.Table.Indexes().List(ctx, opts)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &results)
+	})
 	if err != nil {
-		collection.reset()
 		return nil, err
 	}
-	collection.reset()
 	return results, nil
 }
 
 //DropIndex 删除索引
-func (collection *collection) DropIndex(name string, opts *options.DropIndexesOptions) error {
-	ctx := context.Background()
-	indexView := collection.Table.Indexes()
-
-	_, err := indexView.DropOne(ctx, name, opts)
-	if err != nil {
-		collection.reset()
+func (query *Query) DropIndex(name string, opts *options.DropIndexesOptions) error {
+	return query.run(query.baseContext(), "DropIndex", func(ctx context.Context) error {
+		_, err := query.Table.Indexes().DropOne(ctx, name, opts)
 		return err
-	}
-	collection.reset()
-	return nil
+	})
 }
 
 // 写入单条数据
-func (collection *collection) InsertOne(document interface{}) (*mongo.InsertOneResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err := collection.Table.InsertOne(ctx, BeforeCreate(document))
-	collection.reset()
-	return result, err
+func (query *Query) InsertOne(document interface{}) (result *mongo.InsertOneResult, err error) {
+	generated := generatedID(document)
+	data := BeforeCreate(document)
+	query.applyExpiry(data)
+	if err = query.checkSize(data); err != nil {
+		return
+	}
+	if err = query.checkShardInsert(data); err != nil {
+		return
+	}
+	err = query.do(query.baseContext(), "InsertOne", func(ctx context.Context) (err error) {
+		result, err = query.insertOne(ctx, data, generated)
+		return
+	})
+	return
 }
 
 // 写入多条数据
-func (collection *collection) InsertMany(documents interface{}) (*mongo.InsertManyResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	var data []interface{}
-	data = BeforeCreate(documents).([]interface{})
-	result, err := collection.Table.InsertMany(ctx, data)
-	collection.reset()
-	return result, err
-}
-
-func (collection *collection) Aggregate(pipeline interface{}, result interface{}) (err error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	cursor, err := collection.Table.Aggregate(ctx, pipeline)
-	if err != nil {
-		collection.reset()
+func (query *Query) InsertMany(documents interface{}) (result *mongo.InsertManyResult, err error) {
+	generated := generatedIDs(documents)
+	data := BeforeCreate(documents).([]interface{})
+	query.applyExpiry(data...)
+	if err = query.checkSize(data...); err != nil {
 		return
 	}
-	err = cursor.All(ctx, result)
-	if err != nil {
-		collection.reset()
+	if err = query.checkShardInsert(data...); err != nil {
 		return
 	}
-	collection.reset()
+	err = query.do(query.baseContext(), "InsertMany", func(ctx context.Context) (err error) {
+		result, err = query.insertMany(ctx, data, generated)
+		return
+	})
 	return
 }
 
-// 存在更新,不存在写入, documents 里边的文档需要有 _id 的存在
-func (collection *collection) UpdateOrInsert(documents []interface{}) (*mongo.UpdateResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	var upsert = true
-	result, err := collection.Table.UpdateMany(ctx, collection.filter, documents, &options.UpdateOptions{Upsert: &upsert})
-	collection.reset()
-	return result, err
+func (query *Query) Aggregate(pipeline interface{}, result interface{}) (err error) {
+	return query.do(query.baseContext(), "Aggregate", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, query.aggregateOptions(ctx))
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, result)
+	})
 }
 
+// 存在更新,不存在写入, documents 里边的文档需要有 _id 的存在
 //
-func (collection *collection) UpdateOne(document interface{}) (*mongo.UpdateResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err := collection.Table.UpdateOne(ctx, collection.filter, bson.M{"$set": BeforeUpdate(document)})
+// Deprecated: documents 被直接当作 UpdateMany 的更新内容, 不能正确地按单个文档 upsert, 请使用 Upsert
+func (query *Query) UpdateOrInsert(documents []interface{}) (result *mongo.UpdateResult, err error) {
+	if err = query.checkSize(documents...); err != nil {
+		return
+	}
+	if err = query.checkShardUpdate(query.filter, documents); err != nil {
+		return
+	}
+	err = query.do(query.baseContext(), "UpdateOrInsert", func(ctx context.Context) (err error) {
+		var upsert = true
+		result, err = query.Table.UpdateMany(ctx, query.filter, documents, &options.UpdateOptions{Upsert: &upsert, Collation: query.collation, Comment: commentValue(ctx)})
+		return
+	})
+	return
+}
 
-	collection.reset()
-	return result, err
+//
+func (query *Query) UpdateOne(document interface{}) (result *mongo.UpdateResult, err error) {
+	update := bson.M{"$set": BeforeUpdate(document)}
+	if err = query.checkSize(update); err != nil {
+		return
+	}
+	if err = query.checkShardUpdate(query.filter, update); err != nil {
+		return
+	}
+	err = query.do(query.baseContext(), "UpdateOne", func(ctx context.Context) (err error) {
+		result, err = query.Table.UpdateOne(ctx, query.filter, update, &options.UpdateOptions{Collation: query.collation, Comment: commentValue(ctx)})
+		return
+	})
+	return
 }
 
 //原生update
-func (collection *collection) UpdateOneRaw(document interface{}, opt ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err := collection.Table.UpdateOne(ctx, collection.filter, document, opt...)
-	collection.reset()
-	return result, err
+func (query *Query) UpdateOneRaw(document interface{}, opt ...*options.UpdateOptions) (result *mongo.UpdateResult, err error) {
+	if err = query.checkSize(document); err != nil {
+		return
+	}
+	if err = query.checkShardUpdate(query.filter, document); err != nil {
+		return
+	}
+	err = query.do(query.baseContext(), "UpdateOneRaw", func(ctx context.Context) (err error) {
+		opts := append([]*options.UpdateOptions{{Collation: query.collation, Comment: commentValue(ctx)}}, opt...)
+		result, err = query.Table.UpdateOne(ctx, query.filter, document, opts...)
+		return
+	})
+	return
 }
 
 //
-func (collection *collection) UpdateMany(document interface{}) (*mongo.UpdateResult, error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err := collection.Table.UpdateMany(ctx, collection.filter, bson.M{"$set": BeforeUpdate(document)})
-
-	collection.reset()
-	return result, err
+func (query *Query) UpdateMany(document interface{}) (result *mongo.UpdateResult, err error) {
+	update := bson.M{"$set": BeforeUpdate(document)}
+	if err = query.checkSize(update); err != nil {
+		return
+	}
+	if err = query.checkShardUpdate(query.filter, update); err != nil {
+		return
+	}
+	err = query.do(query.baseContext(), "UpdateMany", func(ctx context.Context) (err error) {
+		result, err = query.Table.UpdateMany(ctx, query.filter, update, &options.UpdateOptions{Collation: query.collation, Comment: commentValue(ctx)})
+		return
+	})
+	return
 }
 
 // 查询一条数据
-func (collection *collection) FindOne(document interface{}) error {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result := collection.Table.FindOne(ctx, collection.filter, &options.FindOneOptions{
-		Skip:       &collection.skip,
-		Sort:       collection.sort,
-		Projection: collection.fields,
-	})
-	err := result.Decode(document)
-	if err != nil {
-		collection.reset()
-		return err
+func (query *Query) FindOne(document interface{}) error {
+	cache := query.resultCache()
+	key := ""
+	if cache != nil {
+		key = query.cacheKey()
+		if raw, ok := cache.get(key); ok {
+			return query.decodeOutside("FindOne", raw, document)
+		}
 	}
-	collection.reset()
-	return nil
+	return query.do(query.baseContext(), "FindOne", func(ctx context.Context) error {
+		raw, err := query.Table.FindOne(ctx, query.filter, query.findOneOptions(ctx)).Raw()
+		if err != nil {
+			return err
+		}
+		cache.set(key, raw)
+		return query.decode(raw, document)
+	})
 }
 
 // 查询多条数据
-func (collection *collection) FindMany(documents interface{}) (err error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err := collection.Table.Find(ctx, collection.filter, &options.FindOptions{
-		Skip:       &collection.skip,
-		Limit:      &collection.limit,
-		Sort:       collection.sort,
-		Projection: collection.fields,
-	})
-	if err != nil {
-		collection.reset()
-		return
-	}
-	defer result.Close(ctx)
+func (query *Query) FindMany(documents interface{}) (err error) {
 	val := reflect.ValueOf(documents)
 	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
-		err = errors.New("result argument must be a slice address")
-		collection.reset()
-		return
+		return errors.New("result argument must be a slice address")
 	}
-
-	slice := reflect.MakeSlice(val.Elem().Type(), 0, 0)
-	itemTyp := val.Elem().Type().Elem()
-	for result.Next(ctx) {
-		item := reflect.New(itemTyp)
-		err := result.Decode(item.Interface())
+	return query.do(query.baseContext(), "FindMany", func(ctx context.Context) error {
+		result, err := query.Table.Find(ctx, query.filter, query.findOptions(ctx))
 		if err != nil {
-			err = errors.New("result argument must be a slice address")
-			collection.reset()
 			return err
 		}
+		defer result.Close(ctx)
+
+		slice := reflect.MakeSlice(val.Elem().Type(), 0, 0)
+		itemTyp := val.Elem().Type().Elem()
+		for result.Next(ctx) {
+			item := reflect.New(itemTyp)
+			if err := query.decode(result.Current, item.Interface()); err != nil {
+				return err
+			}
 
-		slice = reflect.Append(slice, reflect.Indirect(item))
-	}
-	val.Elem().Set(slice)
-	collection.reset()
-	return
+			slice = reflect.Append(slice, reflect.Indirect(item))
+		}
+		if err := result.Err(); err != nil {
+			return err
+		}
+		val.Elem().Set(slice)
+		return nil
+	})
 }
 
 // 删除数据,并返回删除成功的数量
-func (collection *collection) Delete() (count int64, err error) {
-	if collection.filter == nil || len(collection.filter) == 0 {
+func (query *Query) Delete() (count int64, err error) {
+	if len(query.where) == 0 {
 		err = errors.New("you can't delete all documents, it's very dangerous")
-		collection.reset()
 		return
 	}
 
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err := collection.Table.DeleteMany(ctx, collection.filter)
-	if err != nil {
-		collection.reset()
-		return
-	}
-	count = result.DeletedCount
-	collection.reset()
+	err = query.do(query.baseContext(), "Delete", func(ctx context.Context) error {
+		result, err := query.Table.DeleteMany(ctx, query.filter, &options.DeleteOptions{Collation: query.collation, Comment: commentValue(ctx)})
+		if err != nil {
+			return err
+		}
+		count = result.DeletedCount
+		return nil
+	})
 	return
 }
 
-func (collection *collection) Drop() error {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	err := collection.Table.Drop(ctx)
-	return err
+func (query *Query) Drop() error {
+	return query.do(query.baseContext(), "Drop", func(ctx context.Context) error {
+		return query.Table.Drop(ctx)
+	})
 }
 
-func (collection *collection) Count() (result int64, err error) {
-	ctx, _ := context.WithTimeout(context.Background(), 5*time.Second)
-	result, err = collection.Table.CountDocuments(ctx, collection.filter)
-	if err != nil {
-		collection.reset()
+func (query *Query) Count() (result int64, err error) {
+	err = query.do(query.baseContext(), "Count", func(ctx context.Context) (err error) {
+		result, err = query.Table.CountDocuments(ctx, query.filter, &options.CountOptions{Collation: query.collation, Comment: commentOf(ctx)})
 		return
-	}
-	collection.reset()
+	})
+	return
+}
+
+// Exists 是否存在满足条件的文档, 只取一条且只返回 _id, 比 Count()>0 更轻量
+func (query *Query) Exists(ctx context.Context) (exists bool, err error) {
+	err = query.do(ctx, "Exists", func(ctx context.Context) error {
+		err := query.Table.FindOne(ctx, query.filter, &options.FindOneOptions{
+			Skip:       &query.skip,
+			Sort:       query.sort,
+			Projection: bson.M{"_id": 1},
+			Collation:  query.collation,
+			Comment:    commentOf(ctx),
+		}).Err()
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		exists = err == nil
+		return err
+	})
 	return
 }
 func BeforeCreate(document interface{}) interface{} {
@@ -391,7 +816,11 @@ func BeforeCreate(document interface{}) interface{} {
 	case reflect.Struct:
 		var data = make(bson.M)
 		for i := 0; i < typ.NumField(); i++ {
-			data[typ.Field(i).Tag.Get("bson")] = val.Field(i).Interface()
+			key := fieldKey(typ.Field(i))
+			if key == "-" {
+				continue
+			}
+			data[key] = val.Field(i).Interface()
 		}
 		dataVal := reflect.ValueOf(data)
 		if val.FieldByName("Id").Type() == reflect.TypeOf(primitive.ObjectID{}) {
@@ -440,7 +869,11 @@ func BeforeUpdate(document interface{}) interface{} {
 			if ok {
 				continue
 			}
-			data[typ.Field(i).Tag.Get("bson")] = val.Field(i).Interface()
+			key := fieldKey(typ.Field(i))
+			if key == "-" {
+				continue
+			}
+			data[key] = val.Field(i).Interface()
 		}
 		dataVal := reflect.ValueOf(data)
 		// dataVal.SetMapIndex(reflect.ValueOf("updated_at"), reflect.ValueOf(time.Now().Unix()))
@@ -0,0 +1,168 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// OriginKey 跨连接查询时写入 map 结果的来源连接名字段
+const OriginKey = "_origin"
+
+// MultiClient 同时操作多个命名连接
+type MultiClient struct {
+	configs *Configs
+	names   []string
+}
+
+// MultiQuery 在多个连接的同名集合上执行相同的查询
+type MultiQuery struct {
+	client *MultiClient
+	table  string
+	chain  []func(*Query) *Query
+}
+
+// MultiError 部分连接查询失败, 按连接名记录错误
+type MultiError map[string]error
+
+func (e MultiError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s: %v", name, e[name]))
+	}
+	return "multi query failed on " + strings.Join(parts, "; ")
+}
+
+// Multi 按连接名组合多个连接, 例如按地域拆分的集群:
+//
+//	configs.Multi("us", "eu").Collection("users").Where(filter).FindMany(ctx, &out)
+func (configs *Configs) Multi(names ...string) *MultiClient {
+	return &MultiClient{configs: configs, names: names}
+}
+
+// Collection 得到多个连接上同名集合的查询对象
+func (multi *MultiClient) Collection(table string) *MultiQuery {
+	return &MultiQuery{client: multi, table: table}
+}
+
+// with 追加一个作用于每个连接查询的链式方法
+func (query *MultiQuery) with(fn func(*Query) *Query) *MultiQuery {
+	chain := make([]func(*Query) *Query, 0, len(query.chain)+1)
+	return &MultiQuery{client: query.client, table: query.table, chain: append(append(chain, query.chain...), fn)}
+}
+
+// Where 条件
+func (query *MultiQuery) Where(m bson.D) *MultiQuery {
+	return query.with(func(q *Query) *Query { return q.Where(m) })
+}
+
+// Sort 排序, 只在每个连接内部生效, 合并结果按连接顺序拼接
+func (query *MultiQuery) Sort(sorts bson.D) *MultiQuery {
+	return query.with(func(q *Query) *Query { return q.Sort(sorts) })
+}
+
+// Limit 每个连接各自的条数限制
+func (query *MultiQuery) Limit(n int64) *MultiQuery {
+	return query.with(func(q *Query) *Query { return q.Limit(n) })
+}
+
+// Fields 返回字段
+func (query *MultiQuery) Fields(fields bson.M) *MultiQuery {
+	return query.with(func(q *Query) *Query { return q.Fields(fields) })
+}
+
+// query 构造某个连接上的查询, 连接名未配置或无法创建连接时返回错误
+func (query *MultiQuery) query(name string) (*Query, error) {
+	client, err := query.client.configs.GetMongoDBE(name)
+	if err != nil {
+		return nil, err
+	}
+	q := client.Collection(query.table)
+	for _, fn := range query.chain {
+		q = fn(q)
+	}
+	return q, nil
+}
+
+// FindMany 并发在每个连接上查询并按连接顺序合并结果到 documents(切片指针).
+// 元素为 map 时写入 OriginKey 字段, 为结构体时写入带 `mongodb:"origin"` 标签的字符串字段.
+// 部分连接失败时仍合并成功的结果, 并返回 MultiError
+func (query *MultiQuery) FindMany(ctx context.Context, documents interface{}) error {
+	val := reflect.ValueOf(documents)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return errors.New("result argument must be a slice address")
+	}
+	sliceType := val.Elem().Type()
+	results := make([]reflect.Value, len(query.client.names))
+	errs := make([]error, len(query.client.names))
+	var wg sync.WaitGroup
+	for i, name := range query.client.names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			out := reflect.New(sliceType)
+			results[i] = out.Elem()
+			q, err := query.query(name)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = q.WithContext(ctx).FindMany(out.Interface())
+		}(i, name)
+	}
+	wg.Wait()
+
+	merged := reflect.MakeSlice(sliceType, 0, 0)
+	failed := MultiError{}
+	for i, name := range query.client.names {
+		if errs[i] != nil {
+			failed[name] = errs[i]
+			continue
+		}
+		for j := 0; j < results[i].Len(); j++ {
+			setOrigin(results[i].Index(j), name)
+		}
+		merged = reflect.AppendSlice(merged, results[i])
+	}
+	val.Elem().Set(merged)
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// setOrigin 在结果元素上标记来源连接
+func setOrigin(item reflect.Value, name string) {
+	if item.Kind() == reflect.Ptr {
+		if item.IsNil() {
+			return
+		}
+		item = item.Elem()
+	}
+	switch item.Kind() {
+	case reflect.Map:
+		typ := item.Type()
+		if typ.Key().Kind() == reflect.String && reflect.TypeOf(name).AssignableTo(typ.Elem()) && !item.IsNil() {
+			item.SetMapIndex(reflect.ValueOf(OriginKey).Convert(typ.Key()), reflect.ValueOf(name))
+		}
+	case reflect.Struct:
+		typ := item.Type()
+		for i := 0; i < typ.NumField(); i++ {
+			field := item.Field(i)
+			if typ.Field(i).Tag.Get("mongodb") == "origin" && field.Kind() == reflect.String && field.CanSet() {
+				field.SetString(name)
+			}
+		}
+	}
+}
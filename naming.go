@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+)
+
+// NamingStrategy 根据结构体字段名生成 BSON 字段名
+type NamingStrategy func(field string) string
+
+// FieldNaming 没有 bson 标签的结构体字段使用的命名规则, 读取和写入(包括嵌套结构体)、EnsureIndexes 等都按它命名,
+// 默认与驱动一致转为全小写. 编解码器会缓存结构体的字段名, 应在程序初始化时(执行操作之前)设置
+var FieldNaming NamingStrategy = Lowercase
+
+// Lowercase 全小写: UserName -> username
+func Lowercase(field string) string {
+	return strings.ToLower(field)
+}
+
+// SnakeCase 下划线: UserName -> user_name, HTTPServer -> http_server
+func SnakeCase(field string) string {
+	runes := []rune(field)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// LowerCamel 小驼峰: UserName -> userName, HTTPServer -> httpServer
+func LowerCamel(field string) string {
+	runes := []rune(field)
+	for i := range runes {
+		if !unicode.IsUpper(runes[i]) {
+			break
+		}
+		// 连续大写的缩写保留最后一个大写字母作为下一个单词的开头
+		if i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+			break
+		}
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+// fieldKey 结构体字段对应的 BSON 字段名, 标签为空时使用 FieldNaming
+func fieldKey(field reflect.StructField) string {
+	name := bsonTag(field)
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+	if name != "" {
+		return name
+	}
+	return namedField(field.Name)
+}
+
+// bsonTag 字段的 bson 标签, 与驱动一致, 没有 bson 键且整个标签不含冒号时整个标签视为 bson 标签
+func bsonTag(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("bson")
+	if !ok && !strings.Contains(string(field.Tag), ":") {
+		tag = string(field.Tag)
+	}
+	return tag
+}
+
+// namedField 按 FieldNaming 转换字段名
+func namedField(name string) string {
+	if FieldNaming == nil {
+		return Lowercase(name)
+	}
+	return FieldNaming(name)
+}
+
+// namingTagParser 在驱动默认的标签解析上, 没有指定名称的字段按 FieldNaming 命名
+func namingTagParser(field reflect.StructField) (bsoncodec.StructTags, error) {
+	tags, err := bsoncodec.DefaultStructTagParser(field)
+	if err != nil || tags.Skip {
+		return tags, err
+	}
+	if name := bsonTag(field); name == "" || strings.HasPrefix(name, ",") {
+		tags.Name = namedField(field.Name)
+	}
+	return tags, nil
+}
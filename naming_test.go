@@ -0,0 +1,96 @@
+package mongodb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type namingProfile struct {
+	HomeCity string
+}
+
+type namingUser struct {
+	Id       primitive.ObjectID `bson:"_id"`
+	UserName string
+	Nickname string `bson:"nick,omitempty"`
+	Profile  namingProfile
+}
+
+// withSnakeCase 测试期间使用 SnakeCase, 编解码器按类型缓存字段名, 只对本文件的类型生效
+func withSnakeCase(t *testing.T) {
+	previous := FieldNaming
+	FieldNaming = SnakeCase
+	t.Cleanup(func() { FieldNaming = previous })
+}
+
+func TestNamingRoundTrip(t *testing.T) {
+	withSnakeCase(t)
+	user := namingUser{Id: primitive.NewObjectID(), UserName: "ann", Nickname: "a", Profile: namingProfile{HomeCity: "Oslo"}}
+	data, err := marshal(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := bson.Raw(data)
+	for _, path := range [][]string{{"user_name"}, {"nick"}, {"profile", "home_city"}} {
+		if _, err := raw.LookupErr(path...); err != nil {
+			t.Errorf("field %v missing from %v", path, raw)
+		}
+	}
+	var decoded namingUser
+	if err := unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded != user {
+		t.Errorf("decoded %+v, want %+v", decoded, user)
+	}
+}
+
+// MONGODB_TEST_URL 指向可写的测试实例时运行
+func TestNamingInsertAndFind(t *testing.T) {
+	url := os.Getenv("MONGODB_TEST_URL")
+	if url == "" {
+		t.Skip("MONGODB_TEST_URL not set")
+	}
+	withSnakeCase(t)
+	configs := Default().SetOpt("naming", &Opt{Url: url, Database: "mongodb_test"})
+	client, err := configs.Connect("naming")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Client.Disconnect(context.Background())
+	collection := client.Collection("naming_users")
+	defer collection.Drop()
+
+	if _, err := collection.InsertOne(namingUser{UserName: "ann", Profile: namingProfile{HomeCity: "Oslo"}}); err != nil {
+		t.Fatal(err)
+	}
+	query := collection.Where(bson.D{{Key: "user_name", Value: "ann"}})
+
+	var one namingUser
+	if err := query.FindOne(&one); err != nil {
+		t.Fatal(err)
+	}
+	if one.UserName != "ann" || one.Profile.HomeCity != "Oslo" {
+		t.Errorf("FindOne decoded %+v", one)
+	}
+
+	var many []namingUser
+	if err := query.FindMany(&many); err != nil {
+		t.Fatal(err)
+	}
+	if len(many) != 1 || many[0].UserName != "ann" {
+		t.Errorf("FindMany decoded %+v", many)
+	}
+
+	var into namingUser
+	if err := query.FindInto(context.Background(), &into); err != nil {
+		t.Fatal(err)
+	}
+	if into.UserName != "ann" || into.Profile.HomeCity != "Oslo" {
+		t.Errorf("FindInto decoded %+v", into)
+	}
+}
@@ -0,0 +1,315 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// OfflineWrite 暂存的一次写操作
+type OfflineWrite struct {
+	Collection string `bson:"collection"`
+	// Op insert、update 或 delete
+	Op       string    `bson:"op"`
+	Filter   bson.Raw  `bson:"filter,omitempty"`
+	Document bson.Raw  `bson:"document,omitempty"`
+	Upsert   bool      `bson:"upsert,omitempty"`
+	Time     time.Time `bson:"time"`
+}
+
+// OfflineStore 写操作暂存, 按先进先出回放, 容量满时丢弃最早的记录
+type OfflineStore interface {
+	Push(write OfflineWrite) error
+	// Peek 返回最早的 n 条记录
+	Peek(n int) ([]OfflineWrite, error)
+	// Drop 删除最早的 n 条记录
+	Drop(n int) error
+	Len() int
+}
+
+// MemoryStore 进程内的环形暂存
+type MemoryStore struct {
+	mu       sync.Mutex
+	capacity int
+	writes   []OfflineWrite
+	dropped  int64
+}
+
+// NewMemoryStore 创建最多保存 capacity 条记录的内存暂存
+func NewMemoryStore(capacity int) *MemoryStore {
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	return &MemoryStore{capacity: capacity}
+}
+
+func (store *MemoryStore) Push(write OfflineWrite) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.writes) >= store.capacity {
+		store.writes = store.writes[1:]
+		store.dropped++
+	}
+	store.writes = append(store.writes, write)
+	return nil
+}
+
+func (store *MemoryStore) Peek(n int) ([]OfflineWrite, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if n > len(store.writes) {
+		n = len(store.writes)
+	}
+	return append([]OfflineWrite(nil), store.writes[:n]...), nil
+}
+
+func (store *MemoryStore) Drop(n int) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if n > len(store.writes) {
+		n = len(store.writes)
+	}
+	store.writes = store.writes[n:]
+	return nil
+}
+
+func (store *MemoryStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.writes)
+}
+
+// Dropped 因容量满被丢弃的记录数
+func (store *MemoryStore) Dropped() int64 {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.dropped
+}
+
+// FileStore 持久化到本地文件的暂存, 进程重启后仍可回放. 每次变更整体重写文件, 适合边缘设备上的小容量缓冲
+type FileStore struct {
+	*MemoryStore
+	path string
+}
+
+// NewFileStore 打开或创建暂存文件 path, 最多保存 capacity 条记录
+func NewFileStore(path string, capacity int) (*FileStore, error) {
+	store := &FileStore{MemoryStore: NewMemoryStore(capacity), path: path}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for len(data) > 0 {
+		doc, rest, ok := bsoncore.ReadDocument(data)
+		if !ok {
+			return nil, io.ErrUnexpectedEOF
+		}
+		var write OfflineWrite
+		if err := unmarshal(doc, &write); err != nil {
+			return nil, err
+		}
+		store.MemoryStore.Push(write)
+		data = rest
+	}
+	return store, nil
+}
+
+func (store *FileStore) Push(write OfflineWrite) error {
+	store.MemoryStore.Push(write)
+	return store.save()
+}
+
+func (store *FileStore) Drop(n int) error {
+	store.MemoryStore.Drop(n)
+	return store.save()
+}
+
+// save 写入临时文件后替换, 避免写到一半时断电损坏文件
+func (store *FileStore) save() error {
+	store.mu.Lock()
+	var data []byte
+	for _, write := range store.writes {
+		raw, err := marshal(write)
+		if err != nil {
+			store.mu.Unlock()
+			return err
+		}
+		data = append(data, raw...)
+	}
+	store.mu.Unlock()
+	tmp := store.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.path)
+}
+
+// OfflineQueue 写入失败(网络中断、维护模式等)时暂存写操作, 连接恢复后按顺序回放
+type OfflineQueue struct {
+	client *MongoDBClient
+	store  OfflineStore
+	mu     sync.Mutex
+}
+
+// OfflineQueue 创建使用 store 暂存失败写操作的队列
+func (client *MongoDBClient) OfflineQueue(store OfflineStore) *OfflineQueue {
+	return &OfflineQueue{client: client, store: store}
+}
+
+// InsertOne 写入一条文档, 连接不可用时暂存, 返回是否已暂存
+func (queue *OfflineQueue) InsertOne(ctx context.Context, table string, document interface{}) (bool, error) {
+	raw, err := marshal(BeforeCreate(document))
+	if err != nil {
+		return false, err
+	}
+	return queue.exec(ctx, OfflineWrite{Collection: table, Op: "insert", Document: raw})
+}
+
+// UpdateOne 执行一条原始更新语句, 连接不可用时暂存, 返回是否已暂存
+func (queue *OfflineQueue) UpdateOne(ctx context.Context, table string, filter, update interface{}, upsert bool) (bool, error) {
+	rawFilter, err := marshal(filter)
+	if err != nil {
+		return false, err
+	}
+	rawUpdate, err := marshal(update)
+	if err != nil {
+		return false, err
+	}
+	return queue.exec(ctx, OfflineWrite{Collection: table, Op: "update", Filter: rawFilter, Document: rawUpdate, Upsert: upsert})
+}
+
+// DeleteOne 删除一条文档, 连接不可用时暂存, 返回是否已暂存
+func (queue *OfflineQueue) DeleteOne(ctx context.Context, table string, filter interface{}) (bool, error) {
+	rawFilter, err := marshal(filter)
+	if err != nil {
+		return false, err
+	}
+	return queue.exec(ctx, OfflineWrite{Collection: table, Op: "delete", Filter: rawFilter})
+}
+
+// exec 队列中还有未回放的记录时直接追加, 保证顺序; 否则尝试写入, 连接错误时暂存.
+// 调用方的 ctx 已结束时返回其错误, 不暂存
+func (queue *OfflineQueue) exec(ctx context.Context, write OfflineWrite) (bool, error) {
+	write.Time = time.Now()
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	if queue.store.Len() == 0 {
+		err := queue.apply(ctx, write)
+		if err == nil || !offlineError(err) {
+			return false, err
+		}
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+	}
+	return true, queue.store.Push(write)
+}
+
+// apply 执行一次暂存的写操作
+func (queue *OfflineQueue) apply(ctx context.Context, write OfflineWrite) error {
+	query := queue.client.Collection(write.Collection)
+	return query.do(ctx, "OfflineReplay", func(ctx context.Context) (err error) {
+		switch write.Op {
+		case "insert":
+			_, err = query.Table.InsertOne(ctx, write.Document, options.InsertOne().SetComment(commentValue(ctx)))
+		case "update":
+			_, err = query.Table.UpdateOne(ctx, write.Filter, write.Document, options.Update().SetUpsert(write.Upsert).SetComment(commentValue(ctx)))
+		case "delete":
+			_, err = query.Table.DeleteOne(ctx, write.Filter, options.Delete().SetComment(commentValue(ctx)))
+		default:
+			err = errors.New("unknown offline write op " + write.Op)
+		}
+		return
+	})
+}
+
+// Replay 按顺序回放暂存的写操作, 遇到连接错误、取消或 ctx 结束时停止, 保留剩余记录并返回该错误.
+// 其他错误(如重复键)无法通过重试解决, 记录日志后丢弃. 返回成功回放的数量
+func (queue *OfflineQueue) Replay(ctx context.Context) (replayed int, err error) {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+	for queue.store.Len() > 0 {
+		writes, err := queue.store.Peek(100)
+		if err != nil {
+			return replayed, err
+		}
+		for i, write := range writes {
+			if ctx.Err() != nil {
+				if err := queue.store.Drop(i); err != nil {
+					return replayed, err
+				}
+				return replayed, ctx.Err()
+			}
+			if err := queue.apply(ctx, write); err != nil {
+				if offlineError(err) || errors.Is(err, context.Canceled) {
+					if dropErr := queue.store.Drop(i); dropErr != nil {
+						return replayed, dropErr
+					}
+					return replayed, err
+				}
+				if Log != nil {
+					Log.Warn("MongoDB离线写入回放失败, 已丢弃->", write.Collection, " ", write.Op, " ", err)
+				}
+			} else {
+				replayed++
+			}
+		}
+		if err := queue.store.Drop(len(writes)); err != nil {
+			return replayed, err
+		}
+	}
+	return replayed, nil
+}
+
+// Start 每隔 interval 尝试回放一次, 返回停止函数
+func (queue *OfflineQueue) Start(interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if queue.store.Len() == 0 {
+					continue
+				}
+				n, err := queue.Replay(ctx)
+				if Log == nil || ctx.Err() != nil {
+					continue
+				}
+				if err != nil {
+					Log.Warn("MongoDB离线写入回放中断->", "已回放:", n, " 剩余:", queue.store.Len(), " ", err)
+				} else if n > 0 {
+					Log.Info("MongoDB离线写入已回放->", n, " 剩余:", queue.store.Len())
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// offlineError 连接不可用类的错误, 稍后重试可能成功. 取消只在 Replay 中视为可重试(停止回放、关闭连接),
+// 调用方取消自己的写入时不暂存
+func offlineError(err error) bool {
+	return mongo.IsNetworkError(err) || mongo.IsTimeout(err) ||
+		errors.Is(err, ErrMaintenance) || errors.Is(err, ErrShuttingDown) ||
+		errors.Is(err, context.DeadlineExceeded) || errors.Is(err, mongo.ErrClientDisconnected)
+}
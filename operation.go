@@ -0,0 +1,111 @@
+package mongodb
+
+import (
+	"context"
+	"runtime/pprof"
+	"time"
+)
+
+// available 检查连接当前是否可以执行操作
+func (collection *Collection) available() error {
+	if collection.client.InMaintenance() {
+		return ErrMaintenance
+	}
+	return nil
+}
+
+// do 在带超时的 context 中执行一次操作, 超时依次取 Timeout、Opt.OperationTimeout、默认 5 秒,
+// parent 设置了超时预算时按剩余预算计算(不超过 Timeout 指定的值)
+func (query *Query) do(parent context.Context, method string, fn func(ctx context.Context) error) error {
+	timeout, err := operationTimeout(parent, query.baseTimeout(), query.timeout)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	return query.run(ctx, method, fn)
+}
+
+// doHeld 与 do 相同, 但不再登记进行中的操作, 调用方已通过 acquire 登记, 用于关闭期间仍需完成的收尾写入
+func (query *Query) doHeld(parent context.Context, method string, fn func(ctx context.Context) error) error {
+	timeout, err := operationTimeout(parent, query.baseTimeout(), query.timeout)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+	if err := query.available(); err != nil {
+		return err
+	}
+	if err := query.guard(method); err != nil {
+		return err
+	}
+	return query.execute(ctx, method, fn)
+}
+
+// Timeout 指定单次操作的超时, 覆盖 Opt.OperationTimeout, 用于耗时较长的聚合、批量写入等, d <= 0 恢复默认
+func (query *Query) Timeout(d time.Duration) *Query {
+	query = query.clone()
+	query.timeout = d
+	return query
+}
+
+// baseTimeout 没有超时预算时单次操作的超时
+func (query *Query) baseTimeout() time.Duration {
+	if query.timeout > 0 {
+		return query.timeout
+	}
+	if opt := query.client.opt; opt != nil && opt.OperationTimeout > 0 {
+		return opt.OperationTimeout
+	}
+	return defaultTimeout
+}
+
+// run 执行一次操作并记录统计, 不附加超时, 用于索引等耗时较长的操作
+func (query *Query) run(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	if err := query.available(); err != nil {
+		return err
+	}
+	if err := query.guard(method); err != nil {
+		return err
+	}
+	if err := query.client.acquire(); err != nil {
+		return err
+	}
+	defer query.client.release()
+	return query.execute(ctx, method, fn)
+}
+
+// execute 执行 fn 并记录统计、span 和慢查询, 调用方负责检查连接状态和登记进行中的操作
+func (query *Query) execute(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	ctx = query.sessionContext(ctx)
+	ctx, span := query.startSpan(ctx, method)
+	ctx = traceContext(ctx, span)
+	start := time.Now()
+	// 注入的故障同样计入统计和 span
+	err := query.injectFault(ctx, method)
+	if err == nil {
+		if opt := query.client.opt; opt != nil && opt.PprofLabels {
+			labels := pprof.Labels("mongodb.collection", query.namespace(), "mongodb.method", method)
+			pprof.Do(ctx, labels, func(ctx context.Context) {
+				err = fn(ctx)
+			})
+		} else {
+			err = fn(ctx)
+		}
+	}
+	latency := time.Since(start)
+	finishSpan(span, err)
+	query.written(method)
+	query.client.stats.record(query.namespace(), method, latency, err)
+	query.observeOperation(method, latency, err)
+	query.observeDecode(method, err)
+	query.slowQuery(ctx, method, latency)
+	query.observe(method)
+	return err
+}
+
+// namespace 集合的完整名称 db.collection
+func (collection *Collection) namespace() string {
+	return collection.Database.Name() + "." + collection.Table.Name()
+}
@@ -0,0 +1,115 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otelInstrumentation OpenTelemetry instrumentation 名称
+const otelInstrumentation = "github.com/pm-esd/mongodb"
+
+// otelTracer 基于 OpenTelemetry 的 Tracer, span 属性遵循数据库语义约定
+type otelTracer struct {
+	tracer trace.Tracer
+}
+
+// NewOTelTracer 用 OpenTelemetry 的 TracerProvider 创建 Tracer, 可以直接赋给 Opt.Tracer
+func NewOTelTracer(provider trace.TracerProvider) Tracer {
+	return &otelTracer{tracer: provider.Tracer(otelInstrumentation)}
+}
+
+func (tracer *otelTracer) StartSpan(ctx context.Context, operation string) (context.Context, Span) {
+	ctx, span := tracer.tracer.Start(ctx, operation, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx, &otelSpan{span: span}
+}
+
+// otelSpan 把 SetTag 转换为 OpenTelemetry 属性, db.collection 记为 db.mongodb.collection
+type otelSpan struct {
+	span trace.Span
+}
+
+func (span *otelSpan) SetTag(key string, value interface{}) {
+	if key == "error" {
+		if err, ok := value.(error); ok {
+			span.span.RecordError(err)
+			span.span.SetStatus(codes.Error, err.Error())
+			return
+		}
+	}
+	if key == "db.collection" {
+		key = "db.mongodb.collection"
+	}
+	span.span.SetAttributes(otelAttribute(key, value))
+}
+
+func (span *otelSpan) Finish() {
+	span.span.End()
+}
+
+// TraceID 实现 TraceIDSpan, 使 $comment 与 OpenTelemetry 的 trace ID 一致
+func (span *otelSpan) TraceID() string {
+	if sc := span.span.SpanContext(); sc.HasTraceID() {
+		return sc.TraceID().String()
+	}
+	return ""
+}
+
+func otelAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.Int64(key, v.Milliseconds())
+	case []string:
+		return attribute.StringSlice(key, v)
+	case fmt.Stringer:
+		return attribute.Stringer(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
+
+// tracerHolder atomic.Value 要求存入相同的具体类型
+type tracerHolder struct {
+	tracer Tracer
+}
+
+// SetTracerProvider 为所有连接(包括已创建的连接)设置 OpenTelemetry 追踪, Opt.Tracer 优先; 传入 nil 关闭
+func (configs *Configs) SetTracerProvider(provider trace.TracerProvider) {
+	var tracer Tracer
+	if provider != nil {
+		tracer = NewOTelTracer(provider)
+	}
+	configs.mu.Lock()
+	defer configs.mu.Unlock()
+	configs.tracer = tracer
+	for _, conn := range configs.connections {
+		conn.tracer.Store(tracerHolder{tracer: tracer})
+	}
+}
+
+// activeTracer 连接使用的 Tracer, 没有配置时为 nil
+func (client *MongoDBClient) activeTracer() Tracer {
+	if client.opt != nil && client.opt.Tracer != nil {
+		return client.opt.Tracer
+	}
+	if holder, ok := client.tracer.Load().(tracerHolder); ok {
+		return holder.tracer
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Pagination 分页信息
+type Pagination struct {
+	Total      int64 `json:"total"`
+	TotalPages int64 `json:"total_pages"`
+	Page       int64 `json:"page"`
+	PerPage    int64 `json:"per_page"`
+	HasNext    bool  `json:"has_next"`
+	HasPrev    bool  `json:"has_prev"`
+}
+
+// Paginate 按链式条件和排序查询第 page 页(从 1 开始, 小于 1 按 1 处理), 每页 perPage 条, 结果解码到 out 指向的切片.
+// 查询与计数并行执行, 在会话(事务)中时依次执行
+func (query *Query) Paginate(ctx context.Context, page, perPage int64, out interface{}) (*Pagination, error) {
+	val := reflect.ValueOf(out)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return nil, errors.New("out argument must be a slice address")
+	}
+	if perPage <= 0 {
+		return nil, errors.New("perPage must be positive")
+	}
+	if page < 1 {
+		page = 1
+	}
+	list := query.Skip((page - 1) * perPage).Limit(perPage)
+	fetch := func() error {
+		return list.do(ctx, "Paginate", func(ctx context.Context) error {
+			cursor, err := list.Table.Find(ctx, list.filter, list.findOptions(ctx))
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+			slice := reflect.MakeSlice(val.Elem().Type(), 0, int(perPage))
+			itemTyp := val.Elem().Type().Elem()
+			for cursor.Next(ctx) {
+				item := reflect.New(itemTyp)
+				if err := list.decode(cursor.Current, item.Interface()); err != nil {
+					return err
+				}
+				slice = reflect.Append(slice, item.Elem())
+			}
+			if err := cursor.Err(); err != nil {
+				return err
+			}
+			val.Elem().Set(slice)
+			return nil
+		})
+	}
+	var total int64
+	count := func() error {
+		return query.do(ctx, "Count", func(ctx context.Context) (err error) {
+			opts := &options.CountOptions{Collation: query.collation, Comment: commentOf(ctx)}
+			if query.hint != nil {
+				opts.SetHint(query.hint)
+			}
+			total, err = query.Table.CountDocuments(ctx, query.filter, opts)
+			return
+		})
+	}
+	var fetchErr, countErr error
+	// 会话不能被并发使用
+	if query.session != nil || mongo.SessionFromContext(ctx) != nil {
+		if fetchErr = fetch(); fetchErr == nil {
+			countErr = count()
+		}
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			countErr = count()
+		}()
+		fetchErr = fetch()
+		wg.Wait()
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+	if countErr != nil {
+		return nil, countErr
+	}
+	totalPages := (total + perPage - 1) / perPage
+	return &Pagination{
+		Total:      total,
+		TotalPages: totalPages,
+		Page:       page,
+		PerPage:    perPage,
+		HasNext:    page < totalPages,
+		HasPrev:    page > 1,
+	}, nil
+}
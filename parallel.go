@@ -0,0 +1,147 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// samplesPerPartition 计算分区边界时每个分区抽样的 _id 数量
+const samplesPerPartition = 32
+
+// ParallelScan 按 _id 范围把满足条件的文档分成 partitions 个分区并发读取,
+// handler 会被多个 goroutine 同时调用, 任意一次返回错误即停止全部读取.
+// 要求集合的 _id 为同一种类型, 分区边界通过 $sample 抽样得到
+func (query *Query) ParallelScan(ctx context.Context, partitions int, handler func(bson.M) error) error {
+	if partitions < 1 {
+		partitions = 1
+	}
+	bounds, err := query.partitionBounds(ctx, partitions)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+	for i := 0; i <= len(bounds); i++ {
+		idRange := bson.D{}
+		if i > 0 {
+			idRange = append(idRange, bson.E{Key: "$gte", Value: bounds[i-1]})
+		}
+		if i < len(bounds) {
+			idRange = append(idRange, bson.E{Key: "$lt", Value: bounds[i]})
+		}
+		filter := query.filter
+		if len(idRange) > 0 {
+			filter = bson.D{{Key: "$and", Value: bson.A{query.filter, bson.D{{Key: "_id", Value: idRange}}}}}
+		}
+		wg.Add(1)
+		go func(filter bson.D) {
+			defer wg.Done()
+			err := query.run(ctx, "ParallelScan", func(ctx context.Context) error {
+				cursor, err := query.Table.Find(ctx, filter, &options.FindOptions{
+					Projection: query.projection(ctx),
+					Collation:  query.collation,
+					Comment:    commentOf(ctx),
+				})
+				if err != nil {
+					return err
+				}
+				defer cursor.Close(ctx)
+				for cursor.Next(ctx) {
+					var doc bson.M
+					if err := query.decode(cursor.Current, &doc); err != nil {
+						return err
+					}
+					if err := handler(doc); err != nil {
+						return err
+					}
+				}
+				return cursor.Err()
+			})
+			if err != nil {
+				fail(err)
+			}
+		}(filter)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// partitionBounds 抽样 _id 并取分位点作为分区边界, 返回 partitions-1 个递增的边界值
+func (query *Query) partitionBounds(ctx context.Context, partitions int) ([]interface{}, error) {
+	if partitions == 1 {
+		return nil, nil
+	}
+	var samples []struct {
+		ID interface{} `bson:"_id"`
+	}
+	err := query.do(ctx, "ParallelScan", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, bson.A{
+			bson.D{{Key: "$match", Value: query.filter}},
+			bson.D{{Key: "$sample", Value: bson.D{{Key: "size", Value: partitions * samplesPerPartition}}}},
+			bson.D{{Key: "$project", Value: bson.D{{Key: "_id", Value: 1}}}},
+			bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+		})
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, &options.AggregateOptions{Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &samples)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(samples) < partitions {
+		return nil, nil
+	}
+	for _, sample := range samples[1:] {
+		if !sameIDType(samples[0].ID, sample.ID) {
+			return nil, errors.New("parallel scan requires all _id values to have the same type")
+		}
+	}
+	var bounds []interface{}
+	for i := 1; i < partitions; i++ {
+		bound := samples[i*len(samples)/partitions].ID
+		// 抽样结果已排序, 相同的边界只保留一个
+		if len(bounds) > 0 && reflect.DeepEqual(bounds[len(bounds)-1], bound) {
+			continue
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds, nil
+}
+
+// sameIDType 数值类型之间可以比较, 其余要求类型完全一致
+func sameIDType(a, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return true
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b)
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int32, int64, float64:
+		return true
+	}
+	return false
+}
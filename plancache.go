@@ -0,0 +1,37 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PlanCacheStats 获取集合的查询计划缓存($planCacheStats)
+func (query *Query) PlanCacheStats(ctx context.Context) (results []bson.M, err error) {
+	err = query.do(ctx, "PlanCacheStats", func(ctx context.Context) error {
+		cursor, err := query.Table.Aggregate(ctx, bson.A{bson.D{{Key: "$planCacheStats", Value: bson.D{}}}}, &options.AggregateOptions{Comment: commentOf(ctx)})
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &results)
+	})
+	return
+}
+
+// ClearPlanCache 清除集合的查询计划缓存, 链式设置了条件时只清除该查询形状的缓存
+func (query *Query) ClearPlanCache(ctx context.Context) error {
+	return query.do(ctx, "ClearPlanCache", func(ctx context.Context) error {
+		cmd := bson.D{{Key: "planCacheClear", Value: query.Table.Name()}}
+		if len(query.filter) > 0 {
+			cmd = append(cmd, bson.E{Key: "query", Value: query.filter})
+			if len(query.sort) > 0 {
+				cmd = append(cmd, bson.E{Key: "sort", Value: query.sort})
+			}
+			if query.fields != nil {
+				cmd = append(cmd, bson.E{Key: "projection", Value: query.fields})
+			}
+		}
+		return query.Database.RunCommand(ctx, cmd).Err()
+	})
+}
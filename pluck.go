@@ -0,0 +1,114 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Pluck 按链式条件查询单个字段(支持 a.b 形式), 将值解码到 out 指向的切片, 缺少该字段的文档被忽略
+func (query *Query) Pluck(ctx context.Context, field string, out interface{}) error {
+	val := reflect.ValueOf(out)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return errors.New("out argument must be a slice address")
+	}
+	projection := bson.M{field: 1}
+	if field != "_id" {
+		projection["_id"] = 0
+	}
+	query = query.Fields(projection)
+	path := strings.Split(field, ".")
+	sliceType := val.Elem().Type()
+	return query.do(ctx, "Pluck", func(ctx context.Context) error {
+		cursor, err := query.Table.Find(ctx, query.filter, query.findOptions(ctx))
+		if err != nil {
+			return err
+		}
+		defer cursor.Close(ctx)
+		slice := reflect.MakeSlice(sliceType, 0, 0)
+		for cursor.Next(ctx) {
+			raw, err := cursor.Current.LookupErr(path...)
+			if err != nil {
+				continue
+			}
+			item := reflect.New(sliceType.Elem())
+			if err := unmarshalValue(raw, item.Interface()); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, item.Elem())
+		}
+		if err := cursor.Err(); err != nil {
+			return err
+		}
+		val.Elem().Set(slice)
+		return nil
+	})
+}
+
+// Distinct 按链式条件查询字段(支持 a.b 形式)的不同取值, 解码到 out 指向的切片; 数组字段按元素去重.
+// 配置了 ReadPolicy 时通过聚合执行, 隐藏的字段没有取值
+func (query *Query) Distinct(ctx context.Context, field string, out interface{}) error {
+	val := reflect.ValueOf(out)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Slice {
+		return errors.New("out argument must be a slice address")
+	}
+	sliceType := val.Elem().Type()
+	return query.do(ctx, "Distinct", func(ctx context.Context) error {
+		values, err := query.distinct(ctx, field)
+		if err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(sliceType, 0, len(values))
+		for _, value := range values {
+			raw, err := marshalValue(value)
+			if err != nil {
+				return err
+			}
+			item := reflect.New(sliceType.Elem())
+			if err := unmarshalValue(raw, item.Interface()); err != nil {
+				return err
+			}
+			slice = reflect.Append(slice, item.Elem())
+		}
+		val.Elem().Set(slice)
+		return nil
+	})
+}
+
+func (query *Query) distinct(ctx context.Context, field string) ([]interface{}, error) {
+	rule := query.readRule(ctx)
+	if rule.Redact == nil && len(rule.Hidden) == 0 {
+		opts := options.Distinct().SetComment(commentValue(ctx))
+		if query.collation != nil {
+			opts.SetCollation(query.collation)
+		}
+		return query.Table.Distinct(ctx, field, query.filter, opts)
+	}
+	pipeline, err := query.readPipeline(ctx, bson.A{
+		bson.D{{Key: "$match", Value: query.filter}},
+		bson.D{{Key: "$unwind", Value: "$" + field}},
+		bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$" + field}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := query.Table.Aggregate(ctx, pipeline, query.aggregateOptions(ctx))
+	if err != nil {
+		return nil, err
+	}
+	var groups []struct {
+		Value interface{} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &groups); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, 0, len(groups))
+	for _, group := range groups {
+		values = append(values, group.Value)
+	}
+	return values, nil
+}
@@ -0,0 +1,83 @@
+package mongodb
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// PoolStats 连接池统计
+type PoolStats struct {
+	// Open 当前打开的连接数
+	Open int64 `json:"open"`
+	// InUse 当前被占用的连接数
+	InUse int64 `json:"in_use"`
+	// Idle 当前空闲的连接数
+	Idle int64 `json:"idle"`
+	// CheckedOut 累计获取连接次数
+	CheckedOut int64 `json:"checked_out"`
+	// CheckOutFailed 累计获取连接失败次数
+	CheckOutFailed int64 `json:"check_out_failed"`
+}
+
+// poolCounter 通过 PoolMonitor 统计连接池
+type poolCounter struct {
+	open           int64
+	inUse          int64
+	checkedOut     int64
+	checkOutFailed int64
+	// connection、labels、metrics 用于把事件转交给 MetricsCollector
+	connection string
+	labels     map[string]string
+	metrics    *atomic.Value
+}
+
+func (counter *poolCounter) monitor() *event.PoolMonitor {
+	return &event.PoolMonitor{
+		Event: func(e *event.PoolEvent) {
+			collector := loadMetrics(counter.metrics)
+			observe := func(kind PoolEventKind, wait time.Duration) {
+				if collector != nil {
+					collector.ObservePool(PoolMetric{Connection: counter.connection, Labels: counter.labels, Kind: kind, Wait: wait})
+				}
+			}
+			switch e.Type {
+			case event.ConnectionCreated:
+				atomic.AddInt64(&counter.open, 1)
+				observe(PoolConnectionOpened, 0)
+			case event.ConnectionClosed:
+				atomic.AddInt64(&counter.open, -1)
+				observe(PoolConnectionClosed, 0)
+			case event.GetSucceeded:
+				atomic.AddInt64(&counter.inUse, 1)
+				atomic.AddInt64(&counter.checkedOut, 1)
+				observe(PoolCheckedOut, e.Duration)
+			case event.ConnectionReturned:
+				atomic.AddInt64(&counter.inUse, -1)
+				observe(PoolCheckedIn, 0)
+			case event.GetFailed:
+				atomic.AddInt64(&counter.checkOutFailed, 1)
+				observe(PoolCheckOutFailed, e.Duration)
+			}
+		},
+	}
+}
+
+// PoolStats 获取连接池统计
+func (client *MongoDBClient) PoolStats() PoolStats {
+	counter := client.pool
+	if counter == nil {
+		return PoolStats{}
+	}
+	stats := PoolStats{
+		Open:           atomic.LoadInt64(&counter.open),
+		InUse:          atomic.LoadInt64(&counter.inUse),
+		CheckedOut:     atomic.LoadInt64(&counter.checkedOut),
+		CheckOutFailed: atomic.LoadInt64(&counter.checkOutFailed),
+	}
+	if stats.Idle = stats.Open - stats.InUse; stats.Idle < 0 {
+		stats.Idle = 0
+	}
+	return stats
+}
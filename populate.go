@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Populate 手动关联: 收集 docs 中 refField 字段保存的 _id(单个或切片), 从 targetColl 批量查询后
+// 填充到 as 字段. docs 为结构体切片的指针, refField、as 可以是 bson 标签名或结构体字段名,
+// as 字段可以是结构体、结构体指针或切片, refField 为切片时 as 必须是切片
+func (client *MongoDBClient) Populate(ctx context.Context, docs interface{}, refField, targetColl, as string) error {
+	slice := reflect.ValueOf(docs)
+	if slice.Kind() != reflect.Ptr || slice.Elem().Kind() != reflect.Slice {
+		return errors.New("docs argument must be a slice address")
+	}
+	slice = slice.Elem()
+
+	var ids []interface{}
+	seen := make(map[string]bool)
+	for i := 0; i < slice.Len(); i++ {
+		item := reflect.Indirect(slice.Index(i))
+		ref, ok := structField(item, refField)
+		if !ok {
+			return fmt.Errorf("field %s not found", refField)
+		}
+		for _, id := range refIDs(ref) {
+			if key := idKey(id); !seen[key] {
+				seen[key] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	raws, err := client.Collection(targetColl).Where(bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}).FindRaw(ctx)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]bson.Raw, len(raws))
+	for _, raw := range raws {
+		var id interface{}
+		if err := unmarshalValue(raw.Lookup("_id"), &id); err != nil {
+			return err
+		}
+		found[idKey(id)] = raw
+	}
+
+	for i := 0; i < slice.Len(); i++ {
+		item := reflect.Indirect(slice.Index(i))
+		ref, _ := structField(item, refField)
+		target, ok := structField(item, as)
+		if !ok {
+			return fmt.Errorf("field %s not found", as)
+		}
+		if err := fillRef(target, refIDs(ref), found); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillRef 将查到的文档解码到目标字段
+func fillRef(target reflect.Value, ids []interface{}, found map[string]bson.Raw) error {
+	decode := func(raw bson.Raw, typ reflect.Type) (reflect.Value, error) {
+		elem := typ
+		if typ.Kind() == reflect.Ptr {
+			elem = typ.Elem()
+		}
+		v := reflect.New(elem)
+		if err := unmarshal(raw, v.Interface()); err != nil {
+			return v, err
+		}
+		if typ.Kind() == reflect.Ptr {
+			return v, nil
+		}
+		return v.Elem(), nil
+	}
+	if target.Kind() == reflect.Slice {
+		list := reflect.MakeSlice(target.Type(), 0, len(ids))
+		for _, id := range ids {
+			raw, ok := found[idKey(id)]
+			if !ok {
+				continue
+			}
+			v, err := decode(raw, target.Type().Elem())
+			if err != nil {
+				return err
+			}
+			list = reflect.Append(list, v)
+		}
+		target.Set(list)
+		return nil
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	raw, ok := found[idKey(ids[0])]
+	if !ok {
+		return nil
+	}
+	v, err := decode(raw, target.Type())
+	if err != nil {
+		return err
+	}
+	target.Set(v)
+	return nil
+}
+
+// refIDs 关联字段中的 _id 列表, 零值被忽略
+func refIDs(ref reflect.Value) []interface{} {
+	if ref.Kind() == reflect.Slice && ref.Type().Elem().Kind() != reflect.Uint8 {
+		ids := make([]interface{}, 0, ref.Len())
+		for i := 0; i < ref.Len(); i++ {
+			if id := ref.Index(i).Interface(); !isZeroID(id) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	if id := ref.Interface(); !isZeroID(id) {
+		return []interface{}{id}
+	}
+	return nil
+}
+
+// idKey _id 的比较键, 数值统一比较, 其余类型不同的相同字面值视为不同
+func idKey(id interface{}) string {
+	switch id.(type) {
+	case int, int32, int64, float64:
+		return fmt.Sprintf("number:%v", toFloat64(id))
+	}
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
+// structField 按 bson 标签名或字段名查找结构体字段
+func structField(item reflect.Value, name string) (reflect.Value, bool) {
+	if item.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	typ := item.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if fieldKey(field) == name || field.Name == name {
+			return item.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
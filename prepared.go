@@ -0,0 +1,289 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Placeholder 查询模板中的占位符, 执行时替换为 Bind 的第 n 个参数(从 0 开始)
+type Placeholder int
+
+// Arg 第 index 个绑定参数的占位符
+func Arg(index int) Placeholder {
+	return Placeholder(index)
+}
+
+// QueryTemplate 命名查询模板, Filter、Sort、Fields 中可以使用 Arg 占位符:
+//
+//	client.Prepare("activeUsersByRegion", mongodb.QueryTemplate{
+//		Collection: "users",
+//		Filter:     bson.D{{Key: "status", Value: "active"}, {Key: "region", Value: mongodb.Arg(0)}},
+//		Sort:       bson.D{{Key: "created_at", Value: -1}},
+//	})
+//	client.Query("activeUsersByRegion").Bind(region).Find(ctx, &out)
+type QueryTemplate struct {
+	Collection string
+	Filter     bson.D
+	Sort       bson.D
+	Fields     bson.M
+	Limit      int64
+	Skip       int64
+}
+
+// PreparedQuery 按名称取出的查询模板, 通过 Bind 绑定参数
+type PreparedQuery struct {
+	client   *MongoDBClient
+	name     string
+	template *QueryTemplate
+}
+
+// BoundQuery 绑定参数后的命名查询
+type BoundQuery struct {
+	query *Query
+	err   error
+}
+
+// Prepare 注册命名查询模板, 同名模板会被覆盖
+func (client *MongoDBClient) Prepare(name string, template QueryTemplate) error {
+	if name == "" {
+		return errors.New("prepared query requires a name")
+	}
+	if template.Collection == "" {
+		return fmt.Errorf("prepared query %q requires a collection", name)
+	}
+	client.prepared.Store(name, &template)
+	return nil
+}
+
+// Query 按名称获取已注册的查询模板
+func (client *MongoDBClient) Query(name string) *PreparedQuery {
+	prepared := &PreparedQuery{client: client, name: name}
+	if v, ok := client.prepared.Load(name); ok {
+		prepared.template = v.(*QueryTemplate)
+	}
+	return prepared
+}
+
+// Bind 按位置绑定占位符参数
+func (prepared *PreparedQuery) Bind(args ...interface{}) *BoundQuery {
+	if prepared.template == nil {
+		return &BoundQuery{err: fmt.Errorf("prepared query %q is not registered", prepared.name)}
+	}
+	template := prepared.template
+	filter, err := bind(template.Filter, args)
+	if err != nil {
+		return &BoundQuery{err: fmt.Errorf("prepared query %q: %w", prepared.name, err)}
+	}
+	sort, err := bind(template.Sort, args)
+	if err != nil {
+		return &BoundQuery{err: fmt.Errorf("prepared query %q: %w", prepared.name, err)}
+	}
+	fields, err := bind(template.Fields, args)
+	if err != nil {
+		return &BoundQuery{err: fmt.Errorf("prepared query %q: %w", prepared.name, err)}
+	}
+	query := prepared.client.Collection(template.Collection)
+	if filter, ok := filter.(bson.D); ok && filter != nil {
+		query = query.Where(filter)
+	}
+	if sort, ok := sort.(bson.D); ok && sort != nil {
+		query = query.Sort(sort)
+	}
+	if fields, ok := fields.(bson.M); ok && fields != nil {
+		query = query.Fields(fields)
+	}
+	if template.Limit > 0 {
+		query = query.Limit(template.Limit)
+	}
+	if template.Skip > 0 {
+		query = query.Skip(template.Skip)
+	}
+	return &BoundQuery{query: query}
+}
+
+// Query 绑定后的链式查询对象, 可以继续追加条件
+func (bound *BoundQuery) Query() (*Query, error) {
+	return bound.query, bound.err
+}
+
+// Find 查询多条数据, documents 为切片指针
+func (bound *BoundQuery) Find(ctx context.Context, documents interface{}) error {
+	if bound.err != nil {
+		return bound.err
+	}
+	return bound.query.WithContext(ctx).FindMany(documents)
+}
+
+// FindOne 查询一条数据
+func (bound *BoundQuery) FindOne(ctx context.Context, document interface{}) error {
+	if bound.err != nil {
+		return bound.err
+	}
+	return bound.query.WithContext(ctx).FindOne(document)
+}
+
+// Count 统计满足条件的文档数
+func (bound *BoundQuery) Count(ctx context.Context) (int64, error) {
+	if bound.err != nil {
+		return 0, bound.err
+	}
+	return bound.query.WithContext(ctx).Count()
+}
+
+// bind 替换 value 中的占位符, 替换后仍有占位符(模板中无法遍历的位置, 如结构体字段)时返回错误,
+// 避免 Placeholder 作为整数发送到服务器
+func bind(value interface{}, args []interface{}) (interface{}, error) {
+	bound, err := bindValue(value, args)
+	if err != nil {
+		return nil, err
+	}
+	if index, ok := findPlaceholder(reflect.ValueOf(bound)); ok {
+		return nil, fmt.Errorf("placeholder %d cannot be bound at its position in the template", index)
+	}
+	return bound, nil
+}
+
+var placeholderType = reflect.TypeOf(Placeholder(0))
+
+// bindValue 递归复制模板值并替换占位符, 遍历任意切片、数组和 map(如 []bson.D、[]bson.M),
+// 替换后的值不能放回原类型的容器时(如 []Placeholder)复制为 []interface{} / map[string]interface{}
+func bindValue(value interface{}, args []interface{}) (interface{}, error) {
+	if placeholder, ok := value.(Placeholder); ok {
+		if int(placeholder) < 0 || int(placeholder) >= len(args) {
+			return nil, fmt.Errorf("placeholder %d is not bound (%d arguments)", int(placeholder), len(args))
+		}
+		return args[placeholder], nil
+	}
+	if e, ok := value.(bson.E); ok {
+		bound, err := bindValue(e.Value, args)
+		if err != nil {
+			return nil, err
+		}
+		return bson.E{Key: e.Key, Value: bound}, nil
+	}
+	val := reflect.ValueOf(value)
+	switch val.Kind() {
+	case reflect.Slice, reflect.Array:
+		if val.Kind() == reflect.Slice && val.IsNil() || val.Type().Elem().Kind() == reflect.Uint8 {
+			return value, nil
+		}
+		items := make([]interface{}, val.Len())
+		fits := true
+		for i := range items {
+			bound, err := bindValue(val.Index(i).Interface(), args)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = bound
+			fits = fits && assignable(bound, val.Type().Elem())
+		}
+		if !fits {
+			return items, nil
+		}
+		out := reflect.MakeSlice(reflect.SliceOf(val.Type().Elem()), len(items), len(items))
+		for i, item := range items {
+			if item != nil {
+				out.Index(i).Set(reflect.ValueOf(item))
+			}
+		}
+		if val.Kind() == reflect.Slice {
+			return out.Convert(val.Type()).Interface(), nil
+		}
+		array := reflect.New(val.Type()).Elem()
+		reflect.Copy(array, out)
+		return array.Interface(), nil
+	case reflect.Map:
+		if val.IsNil() {
+			return value, nil
+		}
+		items := make(map[interface{}]interface{}, val.Len())
+		fits := true
+		iter := val.MapRange()
+		for iter.Next() {
+			bound, err := bindValue(iter.Value().Interface(), args)
+			if err != nil {
+				return nil, err
+			}
+			items[iter.Key().Interface()] = bound
+			fits = fits && assignable(bound, val.Type().Elem())
+		}
+		if !fits && val.Type().Key().Kind() == reflect.String {
+			out := make(map[string]interface{}, len(items))
+			for key, item := range items {
+				out[reflect.ValueOf(key).String()] = item
+			}
+			return out, nil
+		}
+		if !fits {
+			return nil, fmt.Errorf("cannot bind placeholders into %v", val.Type())
+		}
+		out := reflect.MakeMapWithSize(val.Type(), len(items))
+		for key, item := range items {
+			itemVal := reflect.Zero(val.Type().Elem())
+			if item != nil {
+				itemVal = reflect.ValueOf(item)
+			}
+			out.SetMapIndex(reflect.ValueOf(key), itemVal)
+		}
+		return out.Interface(), nil
+	}
+	return value, nil
+}
+
+// assignable 绑定后的值可以放回元素类型为 typ 的容器
+func assignable(value interface{}, typ reflect.Type) bool {
+	if value == nil {
+		switch typ.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+			return true
+		}
+		return false
+	}
+	return reflect.TypeOf(value).AssignableTo(typ)
+}
+
+// findPlaceholder 查找值中剩余的占位符, 包括结构体的导出字段
+func findPlaceholder(val reflect.Value) (int, bool) {
+	if !val.IsValid() {
+		return 0, false
+	}
+	if val.Type() == placeholderType {
+		return int(val.Int()), true
+	}
+	switch val.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if val.IsNil() {
+			return 0, false
+		}
+		return findPlaceholder(val.Elem())
+	case reflect.Slice, reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return 0, false
+		}
+		for i := 0; i < val.Len(); i++ {
+			if index, ok := findPlaceholder(val.Index(i)); ok {
+				return index, true
+			}
+		}
+	case reflect.Map:
+		iter := val.MapRange()
+		for iter.Next() {
+			if index, ok := findPlaceholder(iter.Value()); ok {
+				return index, true
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			if val.Type().Field(i).IsExported() {
+				if index, ok := findPlaceholder(val.Field(i)); ok {
+					return index, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,112 @@
+package mongodb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBindTemplates(t *testing.T) {
+	args := []interface{}{"active", 18, []string{"cn", "us"}}
+	tests := []struct {
+		name     string
+		template interface{}
+		want     interface{}
+	}{
+		{
+			name:     "top level",
+			template: bson.D{{Key: "status", Value: Arg(0)}},
+			want:     bson.D{{Key: "status", Value: "active"}},
+		},
+		{
+			name: "or of bson.D slice",
+			template: bson.D{{Key: "$or", Value: []bson.D{
+				{{Key: "status", Value: Arg(0)}},
+				{{Key: "age", Value: bson.D{{Key: "$gte", Value: Arg(1)}}}},
+			}}},
+			want: bson.D{{Key: "$or", Value: []bson.D{
+				{{Key: "status", Value: "active"}},
+				{{Key: "age", Value: bson.D{{Key: "$gte", Value: 18}}}},
+			}}},
+		},
+		{
+			name: "or of bson.M slice",
+			template: bson.D{{Key: "$or", Value: []bson.M{
+				{"status": Arg(0)},
+				{"region": bson.M{"$in": Arg(2)}},
+			}}},
+			want: bson.D{{Key: "$or", Value: []bson.M{
+				{"status": "active"},
+				{"region": bson.M{"$in": []string{"cn", "us"}}},
+			}}},
+		},
+		{
+			name:     "in with placeholder elements",
+			template: bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: []Placeholder{Arg(0), Arg(1)}}}}},
+			want:     bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: []interface{}{"active", 18}}}}},
+		},
+		{
+			name: "nested and inside or",
+			template: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$and", Value: []interface{}{
+					bson.M{"status": Arg(0)},
+					bson.M{"tags": bson.M{"$in": bson.A{Arg(0), "vip"}}},
+				}}},
+			}}},
+			want: bson.D{{Key: "$or", Value: bson.A{
+				bson.D{{Key: "$and", Value: []interface{}{
+					bson.M{"status": "active"},
+					bson.M{"tags": bson.M{"$in": bson.A{"active", "vip"}}},
+				}}},
+			}}},
+		},
+		{
+			name:     "map of placeholders",
+			template: bson.M{"status": map[string]Placeholder{"$eq": Arg(0)}},
+			want:     bson.M{"status": map[string]interface{}{"$eq": "active"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := bind(test.template, args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("bind() = %#v, want %#v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBindErrors(t *testing.T) {
+	type filter struct {
+		Status Placeholder
+	}
+	tests := []struct {
+		name     string
+		template interface{}
+		err      string
+	}{
+		{
+			name:     "missing argument",
+			template: bson.D{{Key: "$or", Value: []bson.D{{{Key: "status", Value: Arg(3)}}}}},
+			err:      "placeholder 3 is not bound",
+		},
+		{
+			name:     "placeholder in struct",
+			template: bson.D{{Key: "$or", Value: []filter{{Status: Arg(0)}}}},
+			err:      "placeholder 0 cannot be bound",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := bind(test.template, []interface{}{"active"})
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("bind() error = %v, want %q", err, test.err)
+			}
+		})
+	}
+}
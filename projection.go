@@ -0,0 +1,76 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FindInto 根据目标结构体的 bson 标签自动生成投影, 只读取结构体需要的字段.
+// result 为结构体指针时查询一条, 为结构体切片指针时查询多条
+func (query *Query) FindInto(ctx context.Context, result interface{}) error {
+	val := reflect.ValueOf(result)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return errors.New("result argument must be a pointer")
+	}
+	typ := val.Elem().Type()
+	many := typ.Kind() == reflect.Slice
+	if many {
+		typ = typ.Elem()
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return errors.New("result argument must point to a struct or a slice of structs")
+	}
+	query = query.Fields(projectionOf(typ)).WithContext(ctx)
+	if many {
+		return query.FindMany(result)
+	}
+	return query.FindOne(result)
+}
+
+// projectionOf 结构体字段对应的投影, 内联字段展开, 没有 _id 字段时排除 _id;
+// 含有内联 map(接收所有剩余字段)时返回 nil 读取全部字段
+func projectionOf(typ reflect.Type) bson.M {
+	projection := bson.M{}
+	if !collectProjection(typ, projection) {
+		return nil
+	}
+	if _, ok := projection["_id"]; !ok {
+		projection["_id"] = 0
+	}
+	return projection
+}
+
+func collectProjection(typ reflect.Type, projection bson.M) bool {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := fieldKey(field)
+		if key == "-" {
+			continue
+		}
+		if strings.Contains(field.Tag.Get("bson"), ",inline") {
+			inner := field.Type
+			for inner.Kind() == reflect.Ptr {
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Map {
+				return false
+			}
+			if inner.Kind() == reflect.Struct && !collectProjection(inner, projection) {
+				return false
+			}
+			continue
+		}
+		projection[key] = 1
+	}
+	return true
+}
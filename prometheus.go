@@ -0,0 +1,131 @@
+package mongodb
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// PrometheusCollector 基于 Prometheus 的 MetricsCollector, 同时实现 prometheus.Collector:
+//
+//	collector := mongodb.NewPrometheusCollector("", "team", "service")
+//	prometheus.MustRegister(collector)
+//	configs.SetMetricsCollector(collector)
+type PrometheusCollector struct {
+	operations   *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	decodes      *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	open         *prometheus.GaugeVec
+	inUse        *prometheus.GaugeVec
+	checkedOut   *prometheus.CounterVec
+	checkOutFail *prometheus.CounterVec
+	wait         *prometheus.HistogramVec
+	labels       []string
+}
+
+var (
+	_ MetricsCollector       = (*PrometheusCollector)(nil)
+	_ DecodeMetricsCollector = (*PrometheusCollector)(nil)
+)
+
+// NewPrometheusCollector 创建 Prometheus 指标, namespace 为指标名前缀, 默认 mongodb;
+// labels 为附加到所有指标上的连接标签名(Opt.Labels 中的键), 连接缺少的标签取空字符串
+func NewPrometheusCollector(namespace string, labels ...string) *PrometheusCollector {
+	if namespace == "" {
+		namespace = "mongodb"
+	}
+	operationLabels := append([]string{"connection", "collection", "method"}, labels...)
+	poolLabels := append([]string{"connection"}, labels...)
+	return &PrometheusCollector{
+		labels: labels,
+		operations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "operations_total", Help: "Number of MongoDB operations.",
+		}, operationLabels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "operation_errors_total", Help: "Number of failed MongoDB operations.",
+		}, operationLabels),
+		decodes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "decode_errors_total", Help: "Number of documents that failed to decode.",
+		}, operationLabels),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "operation_duration_seconds", Help: "Latency of MongoDB operations.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, operationLabels),
+		open: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "pool_open_connections", Help: "Open connections in the pool.",
+		}, poolLabels),
+		inUse: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Name: "pool_in_use_connections", Help: "Connections checked out of the pool.",
+		}, poolLabels),
+		checkedOut: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "pool_checkouts_total", Help: "Number of successful connection checkouts.",
+		}, poolLabels),
+		checkOutFail: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Name: "pool_checkout_failures_total", Help: "Number of failed connection checkouts.",
+		}, poolLabels),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Name: "pool_wait_seconds", Help: "Time spent waiting for a connection.",
+			Buckets: []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1, 5},
+		}, poolLabels),
+	}
+}
+
+func (collector *PrometheusCollector) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		collector.operations, collector.errors, collector.decodes, collector.latency,
+		collector.open, collector.inUse, collector.checkedOut, collector.checkOutFail, collector.wait,
+	}
+}
+
+// Describe 实现 prometheus.Collector
+func (collector *PrometheusCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range collector.collectors() {
+		c.Describe(ch)
+	}
+}
+
+// Collect 实现 prometheus.Collector, 空闲连接数为 open - in_use
+func (collector *PrometheusCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, c := range collector.collectors() {
+		c.Collect(ch)
+	}
+}
+
+// values 指标的标签取值, 依次为 names 与连接标签
+func (collector *PrometheusCollector) values(labels map[string]string, names ...string) []string {
+	values := make([]string, 0, len(names)+len(collector.labels))
+	values = append(values, names...)
+	for _, label := range collector.labels {
+		values = append(values, labels[label])
+	}
+	return values
+}
+
+func (collector *PrometheusCollector) ObserveOperation(metric OperationMetric) {
+	values := collector.values(metric.Labels, metric.Connection, metric.Collection, metric.Method)
+	collector.operations.WithLabelValues(values...).Inc()
+	if metric.Err != nil {
+		collector.errors.WithLabelValues(values...).Inc()
+	}
+	collector.latency.WithLabelValues(values...).Observe(metric.Latency.Seconds())
+}
+
+func (collector *PrometheusCollector) ObserveDecode(metric DecodeMetric) {
+	collector.decodes.WithLabelValues(collector.values(metric.Labels, metric.Connection, metric.Collection, metric.Method)...).Inc()
+}
+
+func (collector *PrometheusCollector) ObservePool(metric PoolMetric) {
+	values := collector.values(metric.Labels, metric.Connection)
+	switch metric.Kind {
+	case PoolConnectionOpened:
+		collector.open.WithLabelValues(values...).Inc()
+	case PoolConnectionClosed:
+		collector.open.WithLabelValues(values...).Dec()
+	case PoolCheckedOut:
+		collector.inUse.WithLabelValues(values...).Inc()
+		collector.checkedOut.WithLabelValues(values...).Inc()
+		collector.wait.WithLabelValues(values...).Observe(metric.Wait.Seconds())
+	case PoolCheckedIn:
+		collector.inUse.WithLabelValues(values...).Dec()
+	case PoolCheckOutFailed:
+		collector.checkOutFail.WithLabelValues(values...).Inc()
+		collector.wait.WithLabelValues(values...).Observe(metric.Wait.Seconds())
+	}
+}
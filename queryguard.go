@@ -0,0 +1,127 @@
+package mongodb
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GuardMode 查询守卫模式, 检查查询条件能否使用已声明的索引
+type GuardMode int
+
+const (
+	// GuardOff 不检查
+	GuardOff GuardMode = iota
+	// GuardWarn 记录警告日志后照常执行
+	GuardWarn
+	// GuardReject 拒绝执行并返回 UnindexedQueryError
+	GuardReject
+)
+
+// guardedMethods 按条件读取或修改文档的操作, 条件无法使用索引时会全表扫描
+var guardedMethods = map[string]bool{
+	"FindOne":           true,
+	"FindMany":          true,
+	"Find":              true,
+	"FindRaw":           true,
+	"FindMap":           true,
+	"ForEach":           true,
+	"FindCursor":        true,
+	"FindEach":          true,
+	"Pluck":             true,
+	"Distinct":          true,
+	"Exists":            true,
+	"Count":             true,
+	"Paginate":          true,
+	"UpdateOne":         true,
+	"UpdateOneRaw":      true,
+	"UpdateMany":        true,
+	"UpdateOrInsert":    true,
+	"UpdateOrCreate":    true,
+	"Upsert":            true,
+	"ReplaceOne":        true,
+	"FirstOrCreate":     true,
+	"Touch":             true,
+	"SetExpireAt":       true,
+	"Persist":           true,
+	"Delete":            true,
+	"FindOneAndUpdate":  true,
+	"FindOneAndReplace": true,
+	"FindOneAndDelete":  true,
+}
+
+// UnindexedQueryError 查询条件不匹配集合声明的任何索引
+type UnindexedQueryError struct {
+	Collection string
+	Method     string
+	// Fields 条件中的字段, 为空表示没有条件
+	Fields []string
+}
+
+func (e *UnindexedQueryError) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("%s %s: query without filter would scan the whole collection", e.Collection, e.Method)
+	}
+	return fmt.Sprintf("%s %s: filter on [%s] does not match any declared index", e.Collection, e.Method, strings.Join(e.Fields, ", "))
+}
+
+// DeclareIndex 声明集合的一个索引(按键顺序), 开启 Opt.QueryGuard 时, 条件包含某个已声明索引的第一个字段才允许执行;
+// _id 条件总是允许
+func (client *MongoDBClient) DeclareIndex(table string, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+	collection := client.handle(table)
+	collection.mu.Lock()
+	collection.indexPatterns = append(collection.indexPatterns, append([]string(nil), keys...))
+	collection.mu.Unlock()
+}
+
+// AllowScan 允许集合上的任意查询, 用于配置表等数据量很小的集合
+func (client *MongoDBClient) AllowScan(table string) {
+	collection := client.handle(table)
+	collection.mu.Lock()
+	collection.allowScan = true
+	collection.mu.Unlock()
+}
+
+// guard 按 Opt.QueryGuard 检查查询条件, 未声明任何索引的集合只允许 _id 条件
+func (query *Query) guard(method string) error {
+	opt := query.client.opt
+	if opt == nil || opt.QueryGuard == GuardOff || !guardedMethods[method] {
+		return nil
+	}
+	query.mu.RLock()
+	allowScan, patterns := query.allowScan, query.indexPatterns
+	query.mu.RUnlock()
+	if allowScan {
+		return nil
+	}
+	shape := shapeOf(query.filter, nil)
+	fields := append(append([]string(nil), shape.equality...), shape.ranges...)
+	if indexedFilter(fields, patterns) {
+		return nil
+	}
+	err := &UnindexedQueryError{Collection: query.namespace(), Method: method, Fields: fields}
+	if opt.QueryGuard == GuardWarn {
+		if Log != nil {
+			Log.Warn("MongoDB查询未命中索引->", err, labelSuffix(query.client.Labels()))
+		}
+		return nil
+	}
+	return err
+}
+
+// indexedFilter 条件字段中包含 _id 或某个索引的第一个字段
+func indexedFilter(fields []string, patterns [][]string) bool {
+	for _, field := range fields {
+		if field == "_id" {
+			return true
+		}
+		for _, pattern := range patterns {
+			if pattern[0] == field {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package mongodb
+
+import (
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultCacheEntries CachePolicy 默认最多缓存条数
+const defaultCacheEntries = 10000
+
+// CachePolicy 集合级查询缓存策略, 对 FindOne 生效, 调用处无需修改
+type CachePolicy struct {
+	// TTL FindOne 结果在进程内缓存的时间, 0 不缓存读取结果
+	TTL time.Duration
+	// MaxEntries 最多缓存条数, 超出时清理过期条目, 仍然超出则清空, 默认 10000
+	MaxEntries int
+	// InvalidateOnWrite 通过该集合句柄执行写操作(插入、更新、删除)后清空缓存
+	InvalidateOnWrite bool
+}
+
+// writeMethods 会修改集合数据的操作, 执行后按 InvalidateOnWrite 清空缓存
+var writeMethods = map[string]bool{
+	"InsertOne":          true,
+	"InsertMany":         true,
+	"InsertManyChunked":  true,
+	"UpdateOrInsert":     true,
+	"UpdateOne":          true,
+	"UpdateOneRaw":       true,
+	"UpdateMany":         true,
+	"UpdateOrCreate":     true,
+	"Upsert":             true,
+	"ReplaceOne":         true,
+	"FirstOrCreate":      true,
+	"Touch":              true,
+	"Delete":             true,
+	"DeleteInBatches":    true,
+	"DeleteByIDs":        true,
+	"UpdateInBatches":    true,
+	"TransformInBatches": true,
+	"PipelineWrite":      true,
+	"CoalescedUpdate":    true,
+	"OfflineReplay":      true,
+	"Drop":               true,
+	"FindOneAndUpdate":   true,
+	"FindOneAndReplace":  true,
+	"FindOneAndDelete":   true,
+	"Bulk":               true,
+	"SetExpireAt":        true,
+	"Persist":            true,
+}
+
+type cacheEntry struct {
+	raw     bson.Raw
+	expires time.Time
+}
+
+// resultCache 集合的进程内结果缓存
+type resultCache struct {
+	policy  CachePolicy
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// SetCachePolicy 设置集合的缓存策略, nil 关闭缓存
+func (collection *Collection) SetCachePolicy(policy *CachePolicy) {
+	var cache *resultCache
+	if policy != nil {
+		cache = &resultCache{policy: *policy, entries: make(map[string]cacheEntry)}
+		if cache.policy.MaxEntries <= 0 {
+			cache.policy.MaxEntries = defaultCacheEntries
+		}
+	}
+	collection.mu.Lock()
+	collection.cache = cache
+	collection.mu.Unlock()
+}
+
+// resultCache 集合当前的缓存, 未设置时为 nil
+func (collection *Collection) resultCache() *resultCache {
+	collection.mu.RLock()
+	defer collection.mu.RUnlock()
+	return collection.cache
+}
+
+// cacheKey 查询条件对应的缓存键, 无法序列化或在会话(事务)中执行时返回空字符串表示不缓存
+func (query *Query) cacheKey() string {
+	if query.session != nil || mongo.SessionFromContext(query.baseContext()) != nil {
+		return ""
+	}
+	raw, err := marshal(bson.D{
+		{Key: "filter", Value: query.filter},
+		{Key: "sort", Value: query.sort},
+		{Key: "fields", Value: query.fields},
+		{Key: "skip", Value: query.skip},
+		{Key: "collation", Value: query.collation},
+		{Key: "min", Value: query.minBound},
+		{Key: "max", Value: query.maxBound},
+		{Key: "returnKey", Value: query.returnKey},
+		{Key: "role", Value: RoleFromContext(query.baseContext())},
+	})
+	if err != nil {
+		return ""
+	}
+	return string(raw)
+}
+
+// written 写操作后按策略清空缓存, 写入失败时也可能已部分生效, 同样清空
+func (query *Query) written(method string) {
+	if !writeMethods[method] {
+		return
+	}
+	if cache := query.resultCache(); cache != nil && cache.policy.InvalidateOnWrite {
+		cache.clear()
+	}
+}
+
+func (cache *resultCache) get(key string) (bson.Raw, bool) {
+	if cache == nil || cache.policy.TTL <= 0 || key == "" {
+		return nil, false
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	entry, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(cache.entries, key)
+		return nil, false
+	}
+	return entry.raw, true
+}
+
+func (cache *resultCache) set(key string, raw bson.Raw) {
+	if cache == nil || cache.policy.TTL <= 0 || key == "" {
+		return
+	}
+	now := time.Now()
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if len(cache.entries) >= cache.policy.MaxEntries {
+		for k, entry := range cache.entries {
+			if now.After(entry.expires) {
+				delete(cache.entries, k)
+			}
+		}
+		if len(cache.entries) >= cache.policy.MaxEntries {
+			cache.entries = make(map[string]cacheEntry)
+		}
+	}
+	cache.entries[key] = cacheEntry{raw: raw, expires: now.Add(cache.policy.TTL)}
+}
+
+func (cache *resultCache) clear() {
+	cache.mu.Lock()
+	cache.entries = make(map[string]cacheEntry)
+	cache.mu.Unlock()
+}
@@ -0,0 +1,155 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ReadRule 某个角色读取某个集合时的限制
+type ReadRule struct {
+	// Hidden 不允许读取的字段, 支持 a.b 形式的嵌套字段, Find 系列和聚合都会生效
+	Hidden []string
+	// Redact $redact 表达式, 结果为 $$DESCEND / $$PRUNE / $$KEEP, 按文档内容裁剪文档或子文档.
+	// 只作用于 Aggregate / AggregateCursor, Find 系列方法无法执行 $redact
+	Redact interface{}
+}
+
+// ReadPolicy 根据 ctx 中的角色返回读取 collection 的限制, 通过 Opt.ReadPolicy 统一配置字段级访问控制
+type ReadPolicy func(ctx context.Context, collection, role string) ReadRule
+
+type roleKey struct{}
+
+// WithRole 为 ctx 上的操作指定调用方角色, 供 ReadPolicy 使用
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// RoleFromContext 取出 ctx 中的调用方角色, 没有时返回空字符串
+func RoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
+
+// readRule 当前操作适用的读取限制, 没有配置 ReadPolicy 时为零值
+func (query *Query) readRule(ctx context.Context) ReadRule {
+	opt := query.client.opt
+	if opt == nil || opt.ReadPolicy == nil {
+		return ReadRule{}
+	}
+	return opt.ReadPolicy(ctx, query.Table.Name(), RoleFromContext(ctx))
+}
+
+// projection 链式投影叠加 ReadPolicy 隐藏字段后的投影
+func (query *Query) projection(ctx context.Context) bson.M {
+	rule := query.readRule(ctx)
+	if len(rule.Hidden) == 0 {
+		return query.fields
+	}
+	return hideFields(query.fields, rule.Hidden)
+}
+
+// hideFields 在投影中隐藏 hidden 字段. 包含型投影去掉隐藏字段及其父字段(宁可少返回也不泄露),
+// 去掉后不再包含任何字段时改为排除型投影; 排除型投影或空投影追加排除项
+func hideFields(fields bson.M, hidden []string) bson.M {
+	projection := make(bson.M, len(fields)+len(hidden))
+	inclusion := false
+	for key, value := range fields {
+		projection[key] = value
+		if key != "_id" && included(value) {
+			inclusion = true
+		}
+	}
+	if inclusion {
+		remaining := false
+		for key, value := range projection {
+			if key == "_id" || !included(value) {
+				continue
+			}
+			if hiddenPath(key, hidden) {
+				delete(projection, key)
+				continue
+			}
+			remaining = true
+		}
+		if remaining {
+			for _, field := range hidden {
+				if field == "_id" {
+					projection["_id"] = 0
+				}
+			}
+			return projection
+		}
+		projection = bson.M{}
+	}
+	for _, field := range hidden {
+		projection[field] = 0
+	}
+	return projection
+}
+
+// hiddenPath key 本身、其父字段或其子字段被隐藏
+func hiddenPath(key string, hidden []string) bool {
+	for _, field := range hidden {
+		if key == field || strings.HasPrefix(key, field+".") || strings.HasPrefix(field, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// included 投影值是否表示包含该字段(1、true 或表达式)
+func included(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case int32:
+		return v != 0
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	}
+	return true
+}
+
+// readPipeline 在聚合管道最前面加上 ReadPolicy 的 $redact 和隐藏字段, 使后续阶段无法读取受限数据
+func (query *Query) readPipeline(ctx context.Context, pipeline interface{}) (interface{}, error) {
+	policy := query.readStages(ctx)
+	if len(policy) == 0 {
+		return pipeline, nil
+	}
+	val := reflect.ValueOf(pipeline)
+	if val.Kind() != reflect.Slice && val.Kind() != reflect.Array {
+		return nil, errors.New("read policy requires the pipeline to be a slice of stages")
+	}
+	stages := make(bson.A, 0, val.Len()+len(policy))
+	stages = append(stages, policy...)
+	for i := 0; i < val.Len(); i++ {
+		stages = append(stages, val.Index(i).Interface())
+	}
+	return stages, nil
+}
+
+// readStages ReadPolicy 对应的 $redact 和隐藏字段阶段, 没有限制时为空.
+// 管道中途换成本集合的其他文档(如 $graphLookup 后 $replaceRoot)时需要在该处再次追加
+func (query *Query) readStages(ctx context.Context) bson.A {
+	rule := query.readRule(ctx)
+	var stages bson.A
+	if rule.Redact != nil {
+		stages = append(stages, bson.D{{Key: "$redact", Value: rule.Redact}})
+	}
+	if len(rule.Hidden) > 0 {
+		project := make(bson.D, 0, len(rule.Hidden))
+		for _, field := range rule.Hidden {
+			project = append(project, bson.E{Key: field, Value: 0})
+		}
+		stages = append(stages, bson.D{{Key: "$project", Value: project}})
+	}
+	return stages
+}
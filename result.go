@@ -0,0 +1,58 @@
+package mongodb
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// UpdateResult 更新/upsert 的结果, 不需要调用方处理驱动类型和 interface{} 类型的 _id
+type UpdateResult struct {
+	MatchedCount  int64
+	ModifiedCount int64
+	UpsertedCount int64
+	// UpsertedID 新建文档的 _id, ObjectID 为十六进制字符串, 没有新建时为空
+	UpsertedID string
+
+	rawUpsertedID interface{}
+}
+
+// NewUpdateResult 转换驱动返回的更新结果, result 为 nil 时返回零值结果
+func NewUpdateResult(result *mongo.UpdateResult) *UpdateResult {
+	if result == nil {
+		return &UpdateResult{}
+	}
+	return &UpdateResult{
+		MatchedCount:  result.MatchedCount,
+		ModifiedCount: result.ModifiedCount,
+		UpsertedCount: result.UpsertedCount,
+		UpsertedID:    idString(result.UpsertedID),
+		rawUpsertedID: result.UpsertedID,
+	}
+}
+
+// WasCreated 是否通过 upsert 新建了文档
+func (result *UpdateResult) WasCreated() bool {
+	return result.UpsertedCount > 0 || result.rawUpsertedID != nil
+}
+
+// RawUpsertedID 新建文档的原始 _id
+func (result *UpdateResult) RawUpsertedID() interface{} {
+	return result.rawUpsertedID
+}
+
+// idString _id 的字符串形式, ObjectID 转为十六进制
+func idString(id interface{}) string {
+	switch v := id.(type) {
+	case nil:
+		return ""
+	case primitive.ObjectID:
+		return v.Hex()
+	case string:
+		return v
+	case primitive.Binary:
+		return fmt.Sprintf("%x", v.Data)
+	}
+	return fmt.Sprint(id)
+}
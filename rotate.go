@@ -0,0 +1,153 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldCipher 字段加密算法, 密文以 BSON binary 保存; 本包不内置加密实现, 由调用方按使用的密钥管理方案实现
+type FieldCipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// KeyRotation 加密字段的密钥轮换任务, 用旧密钥解密、新密钥重新加密
+type KeyRotation struct {
+	// Fields 加密的字段(支持 a.b 形式), 字段不存在或不是 binary 时跳过
+	Fields []string
+	Old    FieldCipher
+	New    FieldCipher
+	// BatchSize 每批处理的文档数, 默认 500
+	BatchSize int
+	// Checkpoint 断点存储, 每批写入成功后保存最后一个 _id, 重新运行时从断点继续
+	Checkpoint Checkpointer
+	// Progress 每批完成后回调
+	Progress func(RotationProgress)
+}
+
+// RotationProgress 密钥轮换进度
+type RotationProgress struct {
+	// Scanned 读取的文档数
+	Scanned int64
+	// Rotated 重新加密的字段数
+	Rotated int64
+	// Skipped 已经是新密钥加密的字段数(例如没有断点时重复执行)
+	Skipped int64
+	// Conflicts 读取后被其他写入修改、本次没有更新的字段数, 再次执行会重新处理
+	Conflicts int64
+	LastID    interface{}
+}
+
+// RotateKeys 按 _id 升序分批遍历链式条件匹配的文档, 重新加密 rotation.Fields. 每个字段以读取时的密文为条件更新,
+// 期间被其他写入修改的字段不会被覆盖. 旧密钥无法解密且新密钥可以解密的字段视为已轮换
+func (query *Query) RotateKeys(ctx context.Context, rotation KeyRotation) (RotationProgress, error) {
+	var progress RotationProgress
+	if len(rotation.Fields) == 0 || rotation.Old == nil || rotation.New == nil {
+		return progress, errors.New("key rotation requires fields and both old and new ciphers")
+	}
+	batchSize := rotation.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+	projection := bson.M{}
+	for _, field := range rotation.Fields {
+		projection[field] = 1
+	}
+	source := query.Sort(bson.D{{Key: "_id", Value: 1}}).Limit(int64(batchSize)).Fields(projection)
+	if rotation.Checkpoint != nil {
+		lastID, err := rotation.Checkpoint.Load(ctx)
+		if err != nil {
+			return progress, err
+		}
+		progress.LastID = lastID
+	}
+	for {
+		batch := source
+		if progress.LastID != nil {
+			batch = batch.and(bson.E{Key: "_id", Value: bson.D{{Key: "$gt", Value: progress.LastID}}})
+		}
+		docs, err := batch.FindRaw(ctx)
+		if err != nil {
+			return progress, err
+		}
+		if len(docs) == 0 {
+			return progress, nil
+		}
+		bulk := query.Bulk().Ordered(false)
+		for _, doc := range docs {
+			id, err := doc.LookupErr("_id")
+			if err != nil {
+				return progress, err
+			}
+			for _, field := range rotation.Fields {
+				ciphertext, ok := rotatableField(doc, field)
+				if !ok {
+					continue
+				}
+				rotated, err := rotation.rotate(ciphertext)
+				if errors.Is(err, errAlreadyRotated) {
+					progress.Skipped++
+					continue
+				}
+				if err != nil {
+					return progress, fmt.Errorf("rotate %s of document %v: %w", field, id, err)
+				}
+				bulk.UpdateOne(
+					bson.D{{Key: "_id", Value: id}, {Key: field, Value: primitive.Binary{Data: ciphertext}}},
+					bson.D{{Key: "$set", Value: bson.D{{Key: field, Value: primitive.Binary{Data: rotated}}}}},
+				)
+			}
+			progress.LastID = id
+		}
+		progress.Scanned += int64(len(docs))
+		if bulk.Len() > 0 {
+			result, err := bulk.Execute(ctx)
+			if err != nil {
+				return progress, err
+			}
+			progress.Rotated += result.ModifiedCount
+			progress.Conflicts += int64(bulk.Len()) - result.MatchedCount
+		}
+		if rotation.Checkpoint != nil {
+			if err := rotation.Checkpoint.Save(ctx, progress.LastID); err != nil {
+				return progress, err
+			}
+		}
+		if rotation.Progress != nil {
+			rotation.Progress(progress)
+		}
+		if len(docs) < batchSize {
+			return progress, nil
+		}
+	}
+}
+
+var errAlreadyRotated = errors.New("field is already encrypted with the new key")
+
+// rotate 用旧密钥解密、新密钥加密
+func (rotation KeyRotation) rotate(ciphertext []byte) ([]byte, error) {
+	plaintext, err := rotation.Old.Decrypt(ciphertext)
+	if err != nil {
+		if _, newErr := rotation.New.Decrypt(ciphertext); newErr == nil {
+			return nil, errAlreadyRotated
+		}
+		return nil, err
+	}
+	return rotation.New.Encrypt(plaintext)
+}
+
+// rotatableField 字段的 binary 密文, 只处理默认子类型
+func rotatableField(doc bson.Raw, field string) ([]byte, bool) {
+	value, err := doc.LookupErr(strings.Split(field, ".")...)
+	if err != nil || value.Type != bsontype.Binary {
+		return nil, false
+	}
+	subtype, data := value.Binary()
+	return data, subtype == bsontype.BinaryGeneric
+}
@@ -0,0 +1,267 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+
+// SchemaFor 根据结构体生成 {$jsonSchema: ...} 校验规则, 可以直接传给 SetValidator:
+//
+//	Status string  `bson:"status" enum:"active,disabled"`
+//	Email  string  `bson:"email" pattern:"^.+@.+$"`
+//	Age    int     `bson:"age" schema:"minimum=0,maximum=150"`
+//	Note   *string `bson:"note"`
+//
+// 非指针且没有 omitempty 的字段为必需字段, 指针、切片、map 允许为 null;
+// enum 为逗号分隔的枚举值, 没有 enum 标签时使用 RegisterEnum 注册的取值, schema 可选 minimum、maximum、minLength、maxLength、minItems、maxItems
+func SchemaFor(model interface{}) bson.M {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return bson.M{"$jsonSchema": bson.M{"bsonType": "object"}}
+	}
+	return bson.M{"$jsonSchema": objectSchema(typ, 0)}
+}
+
+// objectSchema 结构体对应的 object 规则, 内联字段展开到当前层
+func objectSchema(typ reflect.Type, depth int) bson.M {
+	properties := bson.M{}
+	var required []string
+	collectSchema(typ, depth, properties, &required)
+	schema := bson.M{"bsonType": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func collectSchema(typ reflect.Type, depth int, properties bson.M, required *[]string) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		key := fieldKey(sf)
+		if key == "-" {
+			continue
+		}
+		tag := sf.Tag.Get("bson")
+		if strings.Contains(tag, ",inline") {
+			inner := sf.Type
+			for inner.Kind() == reflect.Ptr {
+				inner = inner.Elem()
+			}
+			if inner.Kind() == reflect.Struct {
+				collectSchema(inner, depth, properties, required)
+			}
+			continue
+		}
+		property := valueSchema(sf.Type, depth)
+		applySchemaTags(property, sf)
+		properties[key] = property
+		if sf.Type.Kind() != reflect.Ptr && !strings.Contains(tag, ",omitempty") {
+			*required = append(*required, key)
+		}
+	}
+}
+
+// valueSchema Go 类型对应的规则, 嵌套过深(自引用类型)时不再限制子字段
+func valueSchema(typ reflect.Type, depth int) bson.M {
+	nullable := false
+	for typ.Kind() == reflect.Ptr {
+		nullable = true
+		typ = typ.Elem()
+	}
+	schema := bson.M{}
+	var types []string
+	switch typ {
+	case timeType, dateTimeType:
+		types = []string{"date"}
+	case objectIDType:
+		types = []string{"objectId"}
+	case decimalType:
+		types = []string{"decimal"}
+	}
+	// RegisterNamedEnum 注册的整数枚举以名称存储
+	if values, ok := registeredEnum(typ); ok && len(values) > 0 {
+		if _, named := values[0].(string); named {
+			types = []string{"string"}
+		}
+	}
+	if types == nil {
+		switch typ.Kind() {
+		case reflect.String:
+			types = []string{"string"}
+		case reflect.Bool:
+			types = []string{"bool"}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			types = []string{"int", "long"}
+		case reflect.Float32, reflect.Float64:
+			types = []string{"double", "int", "long"}
+		case reflect.Slice, reflect.Array:
+			nullable = nullable || typ.Kind() == reflect.Slice
+			if typ.Elem().Kind() == reflect.Uint8 {
+				types = []string{"binData"}
+			} else {
+				types = []string{"array"}
+				if depth < fakeDepth {
+					schema["items"] = valueSchema(typ.Elem(), depth+1)
+				}
+			}
+		case reflect.Map:
+			nullable = true
+			types = []string{"object"}
+		case reflect.Struct:
+			if depth < fakeDepth {
+				schema = objectSchema(typ, depth+1)
+			}
+			types = []string{"object"}
+		case reflect.Interface:
+			// 任意类型
+			return schema
+		}
+	}
+	if nullable {
+		types = append(types, "null")
+	}
+	if len(types) == 1 {
+		schema["bsonType"] = types[0]
+	} else {
+		schema["bsonType"] = types
+	}
+	return schema
+}
+
+// applySchemaTags 处理 enum、pattern、schema 标签
+func applySchemaTags(schema bson.M, sf reflect.StructField) {
+	base := sf.Type
+	for base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+	if enum := sf.Tag.Get("enum"); enum != "" {
+		var values bson.A
+		for _, value := range strings.Split(enum, ",") {
+			value = strings.TrimSpace(value)
+			if base.Kind() == reflect.String {
+				values = append(values, value)
+			} else {
+				values = append(values, parseTagValue(value))
+			}
+		}
+		if sf.Type.Kind() == reflect.Ptr {
+			values = append(values, nil)
+		}
+		schema["enum"] = values
+	} else if registered, ok := registeredEnum(base); ok {
+		values := append(bson.A{}, registered...)
+		if sf.Type.Kind() == reflect.Ptr {
+			values = append(values, nil)
+		}
+		schema["enum"] = values
+	}
+	if pattern := sf.Tag.Get("pattern"); pattern != "" {
+		schema["pattern"] = pattern
+	}
+	for _, option := range strings.Split(sf.Tag.Get("schema"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+		if !ok {
+			continue
+		}
+		switch name {
+		case "minimum", "maximum":
+			if n, err := strconv.ParseFloat(value, 64); err == nil {
+				schema[name] = n
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				schema[name] = n
+			}
+		}
+	}
+}
+
+// 校验级别和校验失败时的处理
+const (
+	ValidationStrict   = "strict"
+	ValidationModerate = "moderate"
+	ValidationError    = "error"
+	ValidationWarn     = "warn"
+)
+
+// ValidationOptions 集合校验参数
+type ValidationOptions struct {
+	// Level strict 校验所有写入, moderate 只校验已满足规则的文档, 默认 strict
+	Level string
+	// Action error 拒绝写入, warn 只记录服务器日志, 默认 error
+	Action string
+}
+
+// SetValidator 设置集合的校验规则(如 SchemaFor 的结果), 集合不存在时创建
+func (query *Query) SetValidator(ctx context.Context, validator interface{}, opts *ValidationOptions) error {
+	if opts == nil {
+		opts = &ValidationOptions{}
+	}
+	level, action := opts.Level, opts.Action
+	if level == "" {
+		level = ValidationStrict
+	}
+	if action == "" {
+		action = ValidationError
+	}
+	return query.run(ctx, "SetValidator", func(ctx context.Context) error {
+		err := query.Database.RunCommand(ctx, bson.D{
+			{Key: "collMod", Value: query.Table.Name()},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: level},
+			{Key: "validationAction", Value: action},
+		}).Err()
+		var cmdErr mongo.CommandError
+		// NamespaceNotFound
+		if errors.As(err, &cmdErr) && cmdErr.Code == 26 {
+			err = query.Database.RunCommand(ctx, bson.D{
+				{Key: "create", Value: query.Table.Name()},
+				{Key: "validator", Value: validator},
+				{Key: "validationLevel", Value: level},
+				{Key: "validationAction", Value: action},
+			}).Err()
+		}
+		return err
+	})
+}
+
+// ApplySchema 按 model 生成 $jsonSchema 并设置为集合的校验规则, 使校验与代码中的结构体保持一致
+func (query *Query) ApplySchema(ctx context.Context, model interface{}, opts *ValidationOptions) error {
+	return query.SetValidator(ctx, SchemaFor(model), opts)
+}
+
+// Validator 集合当前的校验规则, 没有时返回 nil
+func (query *Query) Validator(ctx context.Context) (validator bson.Raw, err error) {
+	err = query.do(ctx, "Validator", func(ctx context.Context) error {
+		specs, err := query.Database.ListCollectionSpecifications(ctx, bson.D{{Key: "name", Value: query.Table.Name()}})
+		if err != nil {
+			return err
+		}
+		if len(specs) == 0 {
+			return nil
+		}
+		if value, err := specs[0].Options.LookupErr("validator"); err == nil {
+			validator, _ = value.DocumentOK()
+		}
+		return nil
+	})
+	return
+}
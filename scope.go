@@ -0,0 +1,53 @@
+package mongodb
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// SetDefaultFilter 设置集合的默认条件, 之后该集合上的查询都会附加该条件, 链式调用 Unscoped 时除外:
+//
+//	client.SetDefaultFilter("orders", bson.D{{Key: "archived", Value: false}})
+func (client *MongoDBClient) SetDefaultFilter(table string, filter bson.D) {
+	collection := client.handle(table)
+	collection.mu.Lock()
+	collection.defaultFilter = filter
+	collection.mu.Unlock()
+}
+
+// SetDefaultSort 设置集合的默认排序, 链式调用 Sort 时覆盖
+func (client *MongoDBClient) SetDefaultSort(table string, sort bson.D) {
+	collection := client.handle(table)
+	collection.mu.Lock()
+	collection.defaultSort = sort
+	collection.mu.Unlock()
+}
+
+// Unscoped 不附加集合的默认条件
+func (query *Query) Unscoped() *Query {
+	query = query.clone()
+	query.unscoped = true
+	query.applyScope()
+	return query
+}
+
+// applyScope 合并默认条件与链式条件, 两者有相同字段时用 $and 组合
+func (query *Query) applyScope() {
+	if query.unscoped || len(query.scope) == 0 {
+		query.filter = query.where
+		return
+	}
+	if len(query.where) == 0 {
+		query.filter = query.scope
+		return
+	}
+	keys := make(map[string]bool, len(query.scope))
+	for _, e := range query.scope {
+		keys[e.Key] = true
+	}
+	for _, e := range query.where {
+		if keys[e.Key] || e.Key == "$and" {
+			query.filter = bson.D{{Key: "$and", Value: bson.A{query.scope, query.where}}}
+			return
+		}
+	}
+	filter := make(bson.D, 0, len(query.scope)+len(query.where))
+	query.filter = append(append(filter, query.scope...), query.where...)
+}
@@ -0,0 +1,323 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeDepth 生成嵌套结构体的最大层数, 避免自引用类型无限递归
+const fakeDepth = 5
+
+var (
+	fakeMu   sync.Mutex
+	fakeRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	fakeWords      = strings.Fields("alpha bravo charlie delta echo foxtrot golf hotel india juliet kilo lima mike november oscar papa quebec romeo sierra tango uniform victor whiskey xray yankee zulu")
+	fakeFirstNames = strings.Fields("James Mary John Linda Robert Susan Michael Karen David Lisa Wei Fang Jun Li Hiroshi Yuki Ahmed Fatima Carlos Sofia")
+	fakeLastNames  = strings.Fields("Smith Johnson Brown Garcia Miller Davis Wang Li Zhang Liu Chen Tanaka Suzuki Khan Silva Martin Müller Rossi Kim Nguyen")
+	fakeCities     = strings.Fields("London Paris Berlin Madrid Rome Tokyo Osaka Beijing Shanghai Shenzhen Seoul Sydney Toronto Chicago Boston Austin Dubai Mumbai Singapore Lisbon")
+	fakeDomains    = strings.Fields("example.com example.org example.net test.io mail.test")
+)
+
+// SetFakeSeed 固定随机数种子, 使 GenerateDocuments 的结果可以复现
+func SetFakeSeed(seed int64) {
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	fakeRand = rand.New(rand.NewSource(seed))
+}
+
+// GenerateDocuments 按 model(结构体或结构体指针)的字段类型生成 n 条随机文档, 返回结构体指针, 可直接传给 InsertMany.
+// 通过 fake 标签指定生成规则, 没有标签时按字段名(email、phone、name 等)和类型推断:
+//
+//	name / first_name / last_name / email / phone / city / url / uuid / word / sentence / hex
+//	int:min,max      整数范围
+//	float:min,max    浮点数范围
+//	oneof:a,b,c      从列举的值中选择(支持字符串和数值字段)
+//	past / future    一年内过去或未来的时间
+//	len:min,max      切片长度, 默认 1 到 3
+//	-                保留零值
+func GenerateDocuments(model interface{}, n int) ([]interface{}, error) {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, errors.New("model must be a struct or a pointer to struct")
+	}
+	fakeMu.Lock()
+	defer fakeMu.Unlock()
+	documents := make([]interface{}, n)
+	for i := range documents {
+		doc := reflect.New(typ)
+		if err := fakeStruct(doc.Elem(), 0); err != nil {
+			return nil, err
+		}
+		documents[i] = doc.Interface()
+	}
+	return documents, nil
+}
+
+// SeedRandom 按 model 生成 n 条随机文档并分批写入集合, 用于压测准备数据, 返回写入成功的数量
+func (query *Query) SeedRandom(ctx context.Context, n int, model interface{}) (inserted int, err error) {
+	for inserted < n {
+		size := n - inserted
+		if size > DefaultChunkSize {
+			size = DefaultChunkSize
+		}
+		documents, err := GenerateDocuments(model, size)
+		if err != nil {
+			return inserted, err
+		}
+		result, err := query.InsertManyChunked(ctx, documents, size)
+		if result != nil {
+			inserted += len(result.InsertedIDs)
+		}
+		if err != nil {
+			return inserted, err
+		}
+		if err := result.Err(); err != nil {
+			return inserted, err
+		}
+	}
+	return inserted, nil
+}
+
+func fakeStruct(val reflect.Value, depth int) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		hint := field.Tag.Get("fake")
+		key := fieldKey(field)
+		if hint == "-" || key == "-" {
+			continue
+		}
+		if hint == "" {
+			hint = fakeHintOf(key)
+		}
+		if err := fakeValue(val.Field(i), key, hint, depth); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// fakeHintOf 没有 fake 标签时按字段名推断生成规则
+func fakeHintOf(key string) string {
+	name := strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	switch {
+	case name == "id":
+		return "hex"
+	case strings.Contains(name, "email"):
+		return "email"
+	case strings.Contains(name, "phone") || strings.Contains(name, "mobile"):
+		return "phone"
+	case name == "firstname":
+		return "first_name"
+	case name == "lastname":
+		return "last_name"
+	case strings.HasSuffix(name, "name"):
+		return "name"
+	case strings.Contains(name, "city"):
+		return "city"
+	case strings.HasSuffix(name, "url") || strings.Contains(name, "website"):
+		return "url"
+	case strings.Contains(name, "uuid"):
+		return "uuid"
+	case strings.Contains(name, "desc") || strings.Contains(name, "content") || strings.Contains(name, "remark"):
+		return "sentence"
+	case strings.HasSuffix(name, "at") || strings.Contains(name, "time") || strings.Contains(name, "date"):
+		return "past"
+	}
+	return ""
+}
+
+func fakeValue(val reflect.Value, key, hint string, depth int) error {
+	kind, args := hint, ""
+	if i := strings.Index(hint, ":"); i >= 0 {
+		kind, args = hint[:i], hint[i+1:]
+	}
+	if kind == "oneof" {
+		return fakeOneOf(val, strings.Split(args, ","))
+	}
+	switch val.Interface().(type) {
+	case time.Time:
+		offset := time.Duration(fakeRand.Int63n(int64(365 * 24 * time.Hour)))
+		if kind == "future" {
+			val.Set(reflect.ValueOf(time.Now().Add(offset).Truncate(time.Millisecond)))
+		} else {
+			val.Set(reflect.ValueOf(time.Now().Add(-offset).Truncate(time.Millisecond)))
+		}
+		return nil
+	case primitive.DateTime:
+		val.Set(reflect.ValueOf(primitive.NewDateTimeFromTime(time.Now().Add(-time.Duration(fakeRand.Int63n(int64(365 * 24 * time.Hour)))))))
+		return nil
+	case primitive.ObjectID:
+		val.Set(reflect.ValueOf(primitive.NewObjectID()))
+		return nil
+	}
+	switch val.Kind() {
+	case reflect.String:
+		val.SetString(fakeString(kind))
+	case reflect.Bool:
+		val.SetBool(fakeRand.Intn(2) == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		min, max := fakeRange(kind, args, 0, 1000)
+		n := int64(min) + fakeRand.Int63n(int64(max-min)+1)
+		if val.OverflowInt(n) {
+			n = 0
+		}
+		val.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		min, max := fakeRange(kind, args, 0, 1000)
+		if min < 0 {
+			min = 0
+		}
+		n := uint64(min) + uint64(fakeRand.Int63n(int64(max-min)+1))
+		if val.OverflowUint(n) {
+			n = 0
+		}
+		val.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		min, max := fakeRange(kind, args, 0, 1000)
+		val.SetFloat(min + fakeRand.Float64()*(max-min))
+	case reflect.Ptr:
+		if depth >= fakeDepth {
+			return nil
+		}
+		elem := reflect.New(val.Type().Elem())
+		if err := fakeValue(elem.Elem(), key, hint, depth); err != nil {
+			return err
+		}
+		val.Set(elem)
+	case reflect.Struct:
+		return fakeStruct(val, depth+1)
+	case reflect.Slice:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, 16)
+			fakeRand.Read(data)
+			val.SetBytes(data)
+			return nil
+		}
+		if depth >= fakeDepth {
+			return nil
+		}
+		min, max := 1.0, 3.0
+		elemHint := hint
+		if kind == "len" {
+			min, max = fakeRange(kind, args, 1, 3)
+			elemHint = ""
+		}
+		n := int(min) + fakeRand.Intn(int(max-min)+1)
+		slice := reflect.MakeSlice(val.Type(), n, n)
+		for i := 0; i < n; i++ {
+			if err := fakeValue(slice.Index(i), key, elemHint, depth+1); err != nil {
+				return err
+			}
+		}
+		val.Set(slice)
+	}
+	// map、interface 等无法推断内容的类型保留零值
+	return nil
+}
+
+// fakeOneOf 从 choices 中随机选择一个, 按字段类型转换
+func fakeOneOf(val reflect.Value, choices []string) error {
+	choice := strings.TrimSpace(choices[fakeRand.Intn(len(choices))])
+	switch val.Kind() {
+	case reflect.String:
+		val.SetString(choice)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(choice, 10, 64)
+		if err != nil {
+			return err
+		}
+		val.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(choice, 10, 64)
+		if err != nil {
+			return err
+		}
+		val.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(choice, 64)
+		if err != nil {
+			return err
+		}
+		val.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(choice)
+		if err != nil {
+			return err
+		}
+		val.SetBool(b)
+	default:
+		return fmt.Errorf("oneof is not supported for %s", val.Type())
+	}
+	return nil
+}
+
+// fakeRange 解析 int:min,max / float:min,max / len:min,max, 格式不对时使用默认范围
+func fakeRange(kind, args string, min, max float64) (float64, float64) {
+	if kind != "int" && kind != "float" && kind != "len" {
+		return min, max
+	}
+	parts := strings.Split(args, ",")
+	if len(parts) != 2 {
+		return min, max
+	}
+	lo, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	hi, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err1 != nil || err2 != nil || hi < lo {
+		return min, max
+	}
+	return lo, hi
+}
+
+func fakeString(kind string) string {
+	pick := func(list []string) string { return list[fakeRand.Intn(len(list))] }
+	switch kind {
+	case "name":
+		return pick(fakeFirstNames) + " " + pick(fakeLastNames)
+	case "first_name":
+		return pick(fakeFirstNames)
+	case "last_name":
+		return pick(fakeLastNames)
+	case "email":
+		return strings.ToLower(pick(fakeFirstNames)) + "." + strings.ToLower(pick(fakeLastNames)) +
+			strconv.Itoa(fakeRand.Intn(1000)) + "@" + pick(fakeDomains)
+	case "phone":
+		return fmt.Sprintf("+1-%03d-%03d-%04d", 200+fakeRand.Intn(800), fakeRand.Intn(1000), fakeRand.Intn(10000))
+	case "city":
+		return pick(fakeCities)
+	case "url":
+		return "https://" + pick(fakeDomains) + "/" + pick(fakeWords) + "/" + strconv.Itoa(fakeRand.Intn(10000))
+	case "uuid":
+		b := make([]byte, 16)
+		fakeRand.Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	case "hex":
+		return primitive.NewObjectID().Hex()
+	case "sentence":
+		words := make([]string, 6+fakeRand.Intn(8))
+		for i := range words {
+			words[i] = pick(fakeWords)
+		}
+		sentence := strings.Join(words, " ")
+		return strings.ToUpper(sentence[:1]) + sentence[1:] + "."
+	}
+	return pick(fakeWords)
+}
@@ -0,0 +1,121 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// BalancerStatus 均衡器状态(balancerStatus)
+type BalancerStatus struct {
+	Mode              string `bson:"mode"`
+	InBalancerRound   bool   `bson:"inBalancerRound"`
+	NumBalancerRounds int64  `bson:"numBalancerRounds"`
+}
+
+// ShardChunks 某个分片上的 chunk 数量
+type ShardChunks struct {
+	Shard  string `bson:"_id"`
+	Chunks int64  `bson:"chunks"`
+}
+
+// ChunkDistribution 集合的 chunk 分布
+type ChunkDistribution struct {
+	Namespace string
+	Total     int64
+	Shards    []ShardChunks
+}
+
+func (client *MongoDBClient) admin() *mongo.Database {
+	return client.Client.Database("admin")
+}
+
+// BalancerStatus 查询均衡器状态, 需要连接 mongos
+func (client *MongoDBClient) BalancerStatus(ctx context.Context) (*BalancerStatus, error) {
+	status := &BalancerStatus{}
+	err := client.admin().RunCommand(ctx, bson.D{{Key: "balancerStatus", Value: 1}}).Decode(status)
+	if err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// StartBalancer 启动均衡器
+func (client *MongoDBClient) StartBalancer(ctx context.Context) error {
+	return client.admin().RunCommand(ctx, bson.D{{Key: "balancerStart", Value: 1}}).Err()
+}
+
+// StopBalancer 停止均衡器, 会等待正在进行的迁移结束
+func (client *MongoDBClient) StopBalancer(ctx context.Context) error {
+	return client.admin().RunCommand(ctx, bson.D{{Key: "balancerStop", Value: 1}}).Err()
+}
+
+// ChunkDistribution 统计集合(db.collection)在各分片上的 chunk 数量
+func (client *MongoDBClient) ChunkDistribution(ctx context.Context, namespace string) (*ChunkDistribution, error) {
+	config := client.Client.Database("config")
+
+	// 5.0 开始 config.chunks 按集合 uuid 关联, 不再有 ns 字段
+	match := bson.D{{Key: "ns", Value: namespace}}
+	var collection bson.M
+	err := config.Collection("collections").FindOne(ctx, bson.D{{Key: "_id", Value: namespace}}).Decode(&collection)
+	if err == nil && collection["uuid"] != nil {
+		match = bson.D{{Key: "$or", Value: bson.A{
+			bson.D{{Key: "ns", Value: namespace}},
+			bson.D{{Key: "uuid", Value: collection["uuid"]}},
+		}}}
+	}
+
+	cursor, err := config.Collection("chunks").Aggregate(ctx, bson.A{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$shard"}, {Key: "chunks", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+		bson.D{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	distribution := &ChunkDistribution{Namespace: namespace}
+	if err = cursor.All(ctx, &distribution.Shards); err != nil {
+		return nil, err
+	}
+	for _, shard := range distribution.Shards {
+		distribution.Total += shard.Chunks
+	}
+	return distribution, nil
+}
+
+// AddShardToZone 将分片加入 zone
+func (client *MongoDBClient) AddShardToZone(ctx context.Context, shard, zone string) error {
+	return client.admin().RunCommand(ctx, bson.D{
+		{Key: "addShardToZone", Value: shard},
+		{Key: "zone", Value: zone},
+	}).Err()
+}
+
+// RemoveShardFromZone 将分片移出 zone
+func (client *MongoDBClient) RemoveShardFromZone(ctx context.Context, shard, zone string) error {
+	return client.admin().RunCommand(ctx, bson.D{
+		{Key: "removeShardFromZone", Value: shard},
+		{Key: "zone", Value: zone},
+	}).Err()
+}
+
+// UpdateZoneKeyRange 将集合(db.collection)的分片键范围 [min, max) 关联到 zone
+func (client *MongoDBClient) UpdateZoneKeyRange(ctx context.Context, namespace string, min, max bson.D, zone string) error {
+	return client.admin().RunCommand(ctx, bson.D{
+		{Key: "updateZoneKeyRange", Value: namespace},
+		{Key: "min", Value: min},
+		{Key: "max", Value: max},
+		{Key: "zone", Value: zone},
+	}).Err()
+}
+
+// RemoveZoneKeyRange 取消分片键范围 [min, max) 与 zone 的关联
+func (client *MongoDBClient) RemoveZoneKeyRange(ctx context.Context, namespace string, min, max bson.D) error {
+	return client.admin().RunCommand(ctx, bson.D{
+		{Key: "updateZoneKeyRange", Value: namespace},
+		{Key: "min", Value: min},
+		{Key: "max", Value: max},
+		{Key: "zone", Value: nil},
+	}).Err()
+}
@@ -0,0 +1,233 @@
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// ShardKeyError 写操作缺少分片键或会修改分片键
+type ShardKeyError struct {
+	Collection string
+	Field      string
+	Reason     string
+}
+
+func (e *ShardKeyError) Error() string {
+	return fmt.Sprintf("%s: shard key field %q %s", e.Collection, e.Field, e.Reason)
+}
+
+// SetShardKey 声明集合的分片键, 之后写入的文档必须包含这些字段, 更新不能修改它们(条件中以相同值限定的 $set 除外)
+func (client *MongoDBClient) SetShardKey(table string, fields ...string) {
+	collection := client.handle(table)
+	collection.mu.Lock()
+	collection.shardKey = append([]string(nil), fields...)
+	collection.mu.Unlock()
+}
+
+// RegisterShardKey 按 model 中带 `mongodb:"shardkey"` 标签的字段(按定义顺序)声明集合的分片键
+func (client *MongoDBClient) RegisterShardKey(table string, model interface{}) error {
+	typ := reflect.TypeOf(model)
+	for typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return errors.New("model must be a struct or a pointer to struct")
+	}
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		for _, option := range strings.Split(field.Tag.Get("mongodb"), ",") {
+			if option == "shardkey" {
+				fields = append(fields, fieldKey(field))
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("%s has no field tagged mongodb:\"shardkey\"", typ)
+	}
+	client.SetShardKey(table, fields...)
+	return nil
+}
+
+func (collection *Collection) shardKeyFields() []string {
+	collection.mu.RLock()
+	defer collection.mu.RUnlock()
+	return collection.shardKey
+}
+
+// checkShardInsert 检查写入的文档都包含分片键
+func (query *Query) checkShardInsert(documents ...interface{}) error {
+	fields := query.shardKeyFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	for _, document := range documents {
+		data, err := marshal(document)
+		if err != nil {
+			// 编码错误交给驱动返回
+			return nil
+		}
+		raw := bson.Raw(data)
+		for _, field := range fields {
+			if _, err := raw.LookupErr(strings.Split(field, ".")...); err != nil {
+				return &ShardKeyError{Collection: query.namespace(), Field: field, Reason: "is missing from the inserted document"}
+			}
+		}
+	}
+	return nil
+}
+
+// checkShardUpdate 检查 update(更新操作符文档、替换文档或更新管道)不会修改分片键
+func (query *Query) checkShardUpdate(filter, update interface{}) error {
+	fields := query.shardKeyFields()
+	if len(fields) == 0 {
+		return nil
+	}
+	pins := shardKeyPins(filter, fields)
+	val := reflect.ValueOf(update)
+	if val.Kind() == reflect.Slice && val.Type() != reflect.TypeOf(bson.D{}) && val.Type() != reflect.TypeOf(bson.Raw{}) {
+		for i := 0; i < val.Len(); i++ {
+			stage, err := marshal(val.Index(i).Interface())
+			if err != nil {
+				return nil
+			}
+			if err := query.checkShardOperators(bson.Raw(stage), fields, pins, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	data, err := marshal(update)
+	if err != nil {
+		return nil
+	}
+	raw := bson.Raw(data)
+	elements, err := raw.Elements()
+	if err != nil || len(elements) == 0 {
+		return nil
+	}
+	if strings.HasPrefix(elements[0].Key(), "$") {
+		return query.checkShardOperators(raw, fields, pins, false)
+	}
+	// 替换文档必须带上分片键, 条件中限定了取值时必须相同
+	for _, field := range fields {
+		value, err := raw.LookupErr(strings.Split(field, ".")...)
+		if err != nil {
+			return &ShardKeyError{Collection: query.namespace(), Field: field, Reason: "is missing from the replacement document"}
+		}
+		if pin, ok := pins[field]; ok && !pin.Equal(value) {
+			return &ShardKeyError{Collection: query.namespace(), Field: field, Reason: "cannot be modified by update"}
+		}
+	}
+	return nil
+}
+
+// checkShardOperators 检查更新操作符(或管道阶段 $set/$addFields/$unset)涉及的字段
+func (query *Query) checkShardOperators(update bson.Raw, fields []string, pins map[string]bson.RawValue, pipeline bool) error {
+	elements, err := update.Elements()
+	if err != nil {
+		return nil
+	}
+	for _, element := range elements {
+		operator := element.Key()
+		if operator == "$setOnInsert" {
+			continue
+		}
+		var paths []string
+		var values map[string]bson.RawValue
+		switch value := element.Value(); {
+		case pipeline && operator == "$unset":
+			paths = unsetPaths(value)
+		case value.Type == bsontype.EmbeddedDocument:
+			if pipeline && operator != "$set" && operator != "$addFields" {
+				continue
+			}
+			values = make(map[string]bson.RawValue)
+			children, _ := value.Document().Elements()
+			for _, child := range children {
+				paths = append(paths, child.Key())
+				values[child.Key()] = child.Value()
+			}
+		}
+		settable := operator == "$set" || operator == "$addFields"
+		for _, path := range paths {
+			for _, field := range fields {
+				if path != field && !strings.HasPrefix(field, path+".") && !strings.HasPrefix(path, field+".") {
+					continue
+				}
+				// 条件以相同值限定分片键时允许 $set 原值, 常见于整体 $set 结构体的更新
+				if settable && values != nil {
+					if pin, ok := pins[field]; ok && sameShardValue(path, field, values[path], pin) {
+						continue
+					}
+				}
+				return &ShardKeyError{Collection: query.namespace(), Field: field, Reason: "cannot be modified by update"}
+			}
+		}
+	}
+	return nil
+}
+
+// sameShardValue path 上设置的值中 field 的取值是否等于 pin
+func sameShardValue(path, field string, value, pin bson.RawValue) bool {
+	if path == field {
+		return value.Equal(pin)
+	}
+	if !strings.HasPrefix(field, path+".") || value.Type != bsontype.EmbeddedDocument {
+		return false
+	}
+	inner, err := value.Document().LookupErr(strings.Split(strings.TrimPrefix(field, path+"."), ".")...)
+	return err == nil && inner.Equal(pin)
+}
+
+// unsetPaths 管道 $unset 阶段的字段, 可以是字符串或字符串数组
+func unsetPaths(value bson.RawValue) []string {
+	if path, ok := value.StringValueOK(); ok {
+		return []string{path}
+	}
+	var paths []string
+	if array, ok := value.ArrayOK(); ok {
+		items, _ := array.Values()
+		for _, item := range items {
+			if path, ok := item.StringValueOK(); ok {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths
+}
+
+// shardKeyPins 条件中以等值(或 $eq)限定的分片键取值
+func shardKeyPins(filter interface{}, fields []string) map[string]bson.RawValue {
+	pins := make(map[string]bson.RawValue)
+	if filter == nil {
+		return pins
+	}
+	data, err := marshal(filter)
+	if err != nil {
+		return pins
+	}
+	raw := bson.Raw(data)
+	for _, field := range fields {
+		value, err := raw.LookupErr(field)
+		if err != nil {
+			continue
+		}
+		if value.Type == bsontype.EmbeddedDocument {
+			elements, _ := value.Document().Elements()
+			if len(elements) > 0 && strings.HasPrefix(elements[0].Key(), "$") {
+				if eq, err := value.Document().LookupErr("$eq"); err == nil && len(elements) == 1 {
+					pins[field] = eq
+				}
+				continue
+			}
+		}
+		pins[field] = value
+	}
+	return pins
+}
@@ -0,0 +1,113 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShuttingDown 连接正在关闭, 不再接受新的操作
+var ErrShuttingDown = errors.New("mongodb: connection is shutting down")
+
+// acquire 登记一个进行中的操作, 关闭期间返回 ErrShuttingDown
+func (client *MongoDBClient) acquire() error {
+	client.inflightMu.Lock()
+	defer client.inflightMu.Unlock()
+	if client.shutting {
+		return ErrShuttingDown
+	}
+	client.inflight++
+	return nil
+}
+
+// release 操作结束
+func (client *MongoDBClient) release() {
+	client.inflightMu.Lock()
+	defer client.inflightMu.Unlock()
+	client.inflight--
+	if client.inflight == 0 && client.drained != nil {
+		close(client.drained)
+		client.drained = nil
+	}
+}
+
+// streamContext 变更流、消费循环等长期运行的操作使用的 context, Shutdown 开始时取消, 使 drain 不必等到超时
+func (client *MongoDBClient) streamContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	if client.closing == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-client.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// drain 停止接受新操作并取消长期运行的操作, 等待进行中的操作结束或 ctx 到期
+func (client *MongoDBClient) drain(ctx context.Context) error {
+	client.inflightMu.Lock()
+	if !client.shutting && client.closing != nil {
+		close(client.closing)
+	}
+	client.shutting = true
+	if client.inflight == 0 {
+		client.inflightMu.Unlock()
+		return nil
+	}
+	if client.drained == nil {
+		client.drained = make(chan struct{})
+	}
+	drained := client.drained
+	client.inflightMu.Unlock()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown 优雅关闭所有连接: 新操作返回 ErrShuttingDown, 取消变更流(Broker、Watch、Flags、ConfigStore 等)、
+// 消费者和 GridFS 下载, 等待其他进行中的操作结束(最多到 ctx 截止时间)后断开连接
+func (configs *Configs) Shutdown(ctx context.Context) error {
+	configs.mu.RLock()
+	clients := make([]*MongoDBClient, 0, len(configs.connections))
+	for _, client := range configs.connections {
+		clients = append(clients, client)
+	}
+	configs.mu.RUnlock()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *MongoDBClient) {
+			defer wg.Done()
+			err := client.drain(ctx)
+			if err != nil && Log != nil {
+				Log.Warn("MongoDB关闭时仍有未完成的操作->", client.ConnectionName, " ", err, labelSuffix(client.Labels()))
+			}
+			// 即使等待超时也要断开连接, 断开本身使用独立的 context
+			if disconnectErr := client.Client.Disconnect(context.Background()); err == nil {
+				err = disconnectErr
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(client)
+	}
+	wg.Wait()
+	return firstErr
+}
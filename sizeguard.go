@@ -0,0 +1,44 @@
+package mongodb
+
+import (
+	"fmt"
+)
+
+// MaxDocumentSize 服务端单个文档的大小上限 16MB
+const MaxDocumentSize = 16 * 1024 * 1024
+
+// DocumentTooLargeError 写入前检查到文档编码后超过 16MB
+type DocumentTooLargeError struct {
+	Collection string
+	// Index 文档在批量写入中的下标, 单条写入为 0
+	Index int
+	Size  int
+}
+
+func (e *DocumentTooLargeError) Error() string {
+	return fmt.Sprintf("document %d for %s is %d bytes, exceeds the %d byte limit", e.Index, e.Collection, e.Size, MaxDocumentSize)
+}
+
+// checkSize 开启 DocumentSizeGuard 时测量待写入文档的 BSON 大小,
+// 超过 DocumentSizeWarn 记录警告, 超过 16MB 返回 DocumentTooLargeError
+func (query *Query) checkSize(documents ...interface{}) error {
+	opt := query.client.opt
+	if opt == nil || !opt.DocumentSizeGuard {
+		return nil
+	}
+	for i, document := range documents {
+		raw, err := marshal(document)
+		if err != nil {
+			// 编码错误交给驱动返回
+			return nil
+		}
+		size := len(raw)
+		if size > MaxDocumentSize {
+			return &DocumentTooLargeError{Collection: query.namespace(), Index: i, Size: size}
+		}
+		if opt.DocumentSizeWarn > 0 && size > opt.DocumentSizeWarn && Log != nil {
+			Log.Warn("MongoDB文档过大->", query.namespace(), " ", size, " bytes", labelSuffix(query.client.Labels()))
+		}
+	}
+	return nil
+}
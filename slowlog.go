@@ -0,0 +1,196 @@
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// explainTimeout 后台 explain 的超时时间
+const explainTimeout = 10 * time.Second
+
+// SlowQuery 慢查询记录
+type SlowQuery struct {
+	Collection string        `json:"collection"`
+	Method     string        `json:"method"`
+	Filter     bson.D        `json:"filter"`
+	Sort       bson.D        `json:"sort"`
+	Duration   time.Duration `json:"duration"`
+	Time       time.Time     `json:"time"`
+	TraceID    string        `json:"trace_id,omitempty"`
+	// Labels 连接的标签(Opt.Labels)
+	Labels map[string]string `json:"labels,omitempty"`
+	// Plan 开启 ExplainSlowQueries 时的执行计划摘要, explain 失败时为 nil
+	Plan *PlanSummary `json:"plan,omitempty"`
+}
+
+// PlanSummary explain("executionStats") 的执行计划摘要
+type PlanSummary struct {
+	// Stages 获胜计划的阶段, 例如 "LIMIT <- FETCH <- IXSCAN(name_1)"
+	Stages        string        `json:"stages"`
+	NReturned     int64         `json:"n_returned"`
+	KeysExamined  int64         `json:"keys_examined"`
+	DocsExamined  int64         `json:"docs_examined"`
+	ExecutionTime time.Duration `json:"execution_time"`
+}
+
+func (plan *PlanSummary) String() string {
+	return fmt.Sprintf("%s returned=%d keys=%d docs=%d time=%s",
+		plan.Stages, plan.NReturned, plan.KeysExamined, plan.DocsExamined, plan.ExecutionTime)
+}
+
+// explainable 可以按 filter 解释执行计划的操作
+var explainable = map[string]bool{
+	"FindOne":    true,
+	"FindMany":   true,
+	"Count":      true,
+	"Delete":     true,
+	"UpdateOne":  true,
+	"UpdateMany": true,
+}
+
+// slowQuery 超过慢查询阈值时记录日志, 按配置在后台 explain
+func (query *Query) slowQuery(ctx context.Context, method string, latency time.Duration) {
+	opt := query.client.opt
+	if opt == nil || opt.SlowQueryThreshold <= 0 || latency < opt.SlowQueryThreshold {
+		return
+	}
+	slow := SlowQuery{
+		Collection: query.namespace(),
+		Method:     method,
+		Filter:     query.filter,
+		Sort:       query.sort,
+		Duration:   latency,
+		Time:       time.Now(),
+		TraceID:    TraceIDFromContext(ctx),
+		Labels:     query.client.Labels(),
+	}
+	if Log != nil {
+		Log.Warn("MongoDB慢查询->", slow.Collection, " ", method, " ", latency, " filter:", query.filter, traceSuffix(slow.TraceID), labelSuffix(slow.Labels))
+	}
+	if !opt.ExplainSlowQueries || !explainable[method] {
+		query.reportSlow(slow)
+		return
+	}
+	go func() {
+		plan, err := query.explain()
+		if err != nil {
+			if Log != nil {
+				Log.Warn("MongoDB慢查询explain失败->", slow.Collection, " ", err, traceSuffix(slow.TraceID), labelSuffix(slow.Labels))
+			}
+		} else {
+			slow.Plan = plan
+			if Log != nil {
+				Log.Warn("MongoDB慢查询执行计划->", slow.Collection, " ", method, " ", plan, traceSuffix(slow.TraceID), labelSuffix(slow.Labels))
+			}
+		}
+		query.reportSlow(slow)
+	}()
+}
+
+// reportSlow 记录到最近慢查询并回调 OnSlowQuery
+func (query *Query) reportSlow(slow SlowQuery) {
+	query.client.slowLog.add(slow)
+	if opt := query.client.opt; opt.OnSlowQuery != nil {
+		opt.OnSlowQuery(slow)
+	}
+}
+
+// explain 以 find 命令解释当前查询条件的执行计划
+func (query *Query) explain() (*PlanSummary, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), explainTimeout)
+	defer cancel()
+	find := bson.D{{Key: "find", Value: query.Table.Name()}, {Key: "filter", Value: query.filter}}
+	if len(query.sort) > 0 {
+		find = append(find, bson.E{Key: "sort", Value: query.sort})
+	}
+	if query.fields != nil {
+		find = append(find, bson.E{Key: "projection", Value: query.fields})
+	}
+	if query.collation != nil {
+		find = append(find, bson.E{Key: "collation", Value: bson.Raw(query.collation.ToDocument())})
+	}
+	if query.skip > 0 {
+		find = append(find, bson.E{Key: "skip", Value: query.skip})
+	}
+	if query.limit > 0 {
+		find = append(find, bson.E{Key: "limit", Value: query.limit})
+	}
+	var result bson.M
+	err := query.Database.RunCommand(ctx, bson.D{
+		{Key: "explain", Value: find},
+		{Key: "verbosity", Value: "executionStats"},
+	}).Decode(&result)
+	if err != nil {
+		return nil, err
+	}
+	return summarizePlan(result), nil
+}
+
+// summarizePlan 提取获胜计划的阶段和执行统计
+func summarizePlan(result bson.M) *PlanSummary {
+	plan := &PlanSummary{}
+	winning := asDoc(asDoc(result["queryPlanner"])["winningPlan"])
+	if inner := asDoc(winning["queryPlan"]); inner != nil {
+		winning = inner
+	}
+	var stages []string
+	for stage := winning; stage != nil; {
+		name, _ := stage["stage"].(string)
+		if index, ok := stage["indexName"].(string); ok {
+			name += "(" + index + ")"
+		}
+		stages = append(stages, name)
+		next := asDoc(stage["inputStage"])
+		if next == nil {
+			if inputs, ok := stage["inputStages"].(bson.A); ok && len(inputs) > 0 {
+				next = asDoc(inputs[0])
+			}
+		}
+		stage = next
+	}
+	plan.Stages = strings.Join(stages, " <- ")
+	execution := asDoc(result["executionStats"])
+	plan.NReturned = toInt64(execution["nReturned"])
+	plan.KeysExamined = toInt64(execution["totalKeysExamined"])
+	plan.DocsExamined = toInt64(execution["totalDocsExamined"])
+	plan.ExecutionTime = time.Duration(toInt64(execution["executionTimeMillis"])) * time.Millisecond
+	return plan
+}
+
+// asDoc 将解码出的嵌套文档统一成 bson.M
+func asDoc(v interface{}) bson.M {
+	switch doc := v.(type) {
+	case bson.M:
+		return doc
+	case bson.D:
+		return doc.Map()
+	}
+	return nil
+}
+
+// toInt64 将 BSON 数值转换成 int64
+func toInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case int:
+		return int64(n)
+	}
+	return 0
+}
+
+// traceSuffix 日志中附加的追踪 ID
+func traceSuffix(traceID string) string {
+	if traceID == "" {
+		return ""
+	}
+	return " trace_id:" + traceID
+}
@@ -0,0 +1,91 @@
+package mongodb
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+)
+
+const srvScheme = "mongodb+srv://"
+
+// isSRV 是否为 mongodb+srv 连接串
+func isSRV(url string) bool {
+	return strings.HasPrefix(url, srvScheme)
+}
+
+// validateSRV 校验 SRV 相关配置, mongodb+srv 只允许单个不带端口的主机名
+func validateSRV(config *Opt) error {
+	if !isSRV(config.Url) {
+		if config.SRVMaxHosts > 0 || config.SRVServiceName != "" {
+			return errors.New("SRVMaxHosts and SRVServiceName require a mongodb+srv:// url")
+		}
+		return nil
+	}
+	if config.DirectConnection {
+		return errors.New("direct connection is not supported with mongodb+srv:// url")
+	}
+	host := strings.TrimPrefix(config.Url, srvScheme)
+	if i := strings.IndexAny(host, "/?"); i >= 0 {
+		host = host[:i]
+	}
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if host == "" || strings.Contains(host, ",") {
+		return errors.New("mongodb+srv:// url must contain exactly one host name")
+	}
+	if strings.Contains(host, ":") {
+		return errors.New("mongodb+srv:// url must not contain a port")
+	}
+	return nil
+}
+
+// topologyHosts 拓扑中的主机列表(已排序)
+func topologyHosts(topology description.Topology) []string {
+	hosts := make([]string, 0, len(topology.Servers))
+	for _, server := range topology.Servers {
+		hosts = append(hosts, server.Addr.String())
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func equalHosts(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// hostsChanged 记录 SRV 解析出的主机, 主机列表变化时回调 OnHostsChange
+func hostsChanged(name string, config *Opt) func(*event.TopologyDescriptionChangedEvent) {
+	var (
+		mu    sync.Mutex
+		hosts []string
+	)
+	return func(e *event.TopologyDescriptionChangedEvent) {
+		current := topologyHosts(e.NewDescription)
+		mu.Lock()
+		changed := !equalHosts(hosts, current)
+		hosts = current
+		mu.Unlock()
+		if !changed {
+			return
+		}
+		if Log != nil {
+			Log.Info("MongoDB主机列表->", name, " ", strings.Join(current, ","))
+		}
+		if config.OnHostsChange != nil {
+			config.OnHostsChange(current)
+		}
+	}
+}
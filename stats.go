@@ -0,0 +1,134 @@
+package mongodb
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// statsSamples 每个操作保留的耗时样本数, 分位数按最近的样本计算
+const statsSamples = 1024
+
+// OpStats 某个集合上某类操作的统计. DecodeErrors 包括游标逐条解码和缓存命中时的解码失败, 这两种不计入 Count 和 Errors
+type OpStats struct {
+	Collection   string        `json:"collection"`
+	Method       string        `json:"method"`
+	Count        int64         `json:"count"`
+	Errors       int64         `json:"errors"`
+	DecodeErrors int64         `json:"decode_errors"`
+	P50          time.Duration `json:"p50"`
+	P90          time.Duration `json:"p90"`
+	P99          time.Duration `json:"p99"`
+	Max          time.Duration `json:"max"`
+}
+
+type opKey struct {
+	collection string
+	method     string
+}
+
+type opCounter struct {
+	count   int64
+	errors  int64
+	decodes int64
+	samples []time.Duration
+	next    int
+}
+
+// statsRegistry 进程内的操作统计
+type statsRegistry struct {
+	mu  sync.Mutex
+	ops map[opKey]*opCounter
+}
+
+func newStatsRegistry() *statsRegistry {
+	return &statsRegistry{ops: make(map[opKey]*opCounter)}
+}
+
+// record 记录一次操作, 查询不到文档不算错误
+func (registry *statsRegistry) record(collection, method string, latency time.Duration, err error) {
+	if registry == nil {
+		return
+	}
+	key := opKey{collection: collection, method: method}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	counter := registry.counter(key)
+	counter.count++
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		counter.errors++
+	}
+	if len(counter.samples) < statsSamples {
+		counter.samples = append(counter.samples, latency)
+	} else {
+		counter.samples[counter.next] = latency
+	}
+	counter.next = (counter.next + 1) % statsSamples
+}
+
+// recordDecode 记录一次解码失败
+func (registry *statsRegistry) recordDecode(collection, method string) {
+	if registry == nil {
+		return
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.counter(opKey{collection: collection, method: method}).decodes++
+}
+
+// counter 操作的计数器, 不存在时创建, 调用方持有锁
+func (registry *statsRegistry) counter(key opKey) *opCounter {
+	counter, ok := registry.ops[key]
+	if !ok {
+		counter = &opCounter{samples: make([]time.Duration, 0, statsSamples)}
+		registry.ops[key] = counter
+	}
+	return counter
+}
+
+// snapshot 按集合、操作排序的统计快照
+func (registry *statsRegistry) snapshot() []OpStats {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	stats := make([]OpStats, 0, len(registry.ops))
+	for key, counter := range registry.ops {
+		samples := make([]time.Duration, len(counter.samples))
+		copy(samples, counter.samples)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		stats = append(stats, OpStats{
+			Collection:   key.collection,
+			Method:       key.method,
+			Count:        counter.count,
+			Errors:       counter.errors,
+			DecodeErrors: counter.decodes,
+			P50:          percentile(samples, 0.50),
+			P90:          percentile(samples, 0.90),
+			P99:          percentile(samples, 0.99),
+			Max:          percentile(samples, 1),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Collection != stats[j].Collection {
+			return stats[i].Collection < stats[j].Collection
+		}
+		return stats[i].Method < stats[j].Method
+	})
+	return stats
+}
+
+// percentile 已排序样本的分位数
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(float64(len(sorted)-1) * p)
+	return sorted[i]
+}
+
+// Stats 获取所有连接上的操作统计
+func (configs *Configs) Stats() []OpStats {
+	return configs.stats.snapshot()
+}
@@ -0,0 +1,285 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConsumerOptions 消费者参数
+type ConsumerOptions struct {
+	// Group 消费组名, 每个消费组独立记录消费位置, 同一时间只有一个进程持有消费组
+	Group string
+	// Offsets 保存消费位置的集合, 默认 <集合名>_offsets
+	Offsets *Query
+	// BatchSize 每次拉取的消息数, 默认 100
+	BatchSize int
+	// PollInterval 没有新消息时的轮询间隔, 默认 1 秒
+	PollInterval time.Duration
+	// MaxBackoff 处理失败后重试的最大间隔, 从 PollInterval 开始翻倍, 默认 30 秒
+	MaxBackoff time.Duration
+	// RateLimit 每秒最多处理的消息数, 0 不限制
+	RateLimit float64
+	// Settle 只消费 _id 时间早于该时长之前的消息, 避免多个写入方的消息乱序提交时被跳过, 默认 2 秒
+	Settle time.Duration
+	// LeaseTTL 消费组租约时长, 持有者超过该时长没有续约时其他进程可以接管, 应大于处理一批消息的时间, 默认 30 秒
+	LeaseTTL time.Duration
+}
+
+// Message 一条消息
+type Message struct {
+	ID  interface{}
+	Raw bson.Raw
+
+	query *Query
+}
+
+// Decode 把消息解码到 v, 与查询结果一样经过解码钩子和读取时迁移
+func (message *Message) Decode(v interface{}) error {
+	return message.query.decodeOutside("Consumer", message.Raw, v)
+}
+
+// Consumer 把只追加写入的集合当作消息流消费, 按 _id 顺序投递, 处理成功后记录消费位置, 保证至少一次投递.
+// 消息的 _id 必须是写入时生成的 ObjectID(InsertOne 默认生成)
+type Consumer struct {
+	query   *Query
+	offsets *Query
+	opts    ConsumerOptions
+	owner   primitive.ObjectID
+}
+
+// Consumer 创建消费者, 链式条件用于过滤消息
+func (query *Query) Consumer(opts ConsumerOptions) (*Consumer, error) {
+	if opts.Group == "" {
+		return nil, errors.New("consumer requires a group")
+	}
+	if opts.Offsets == nil {
+		opts.Offsets = query.client.Collection(query.Table.Name() + "_offsets")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 30 * time.Second
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 2 * time.Second
+	}
+	if opts.LeaseTTL <= 0 {
+		opts.LeaseTTL = 30 * time.Second
+	}
+	return &Consumer{
+		query:   query.Sort(bson.D{{Key: "_id", Value: 1}}).Limit(int64(opts.BatchSize)),
+		offsets: opts.Offsets,
+		opts:    opts,
+		owner:   primitive.NewObjectID(),
+	}, nil
+}
+
+// consumerOffset 消费位置 {_id: group, position, owner, lease_until}
+type consumerOffset struct {
+	Position interface{} `bson:"position"`
+}
+
+// Run 持续消费直到 ctx 结束(返回 ctx 的错误)或读写消费位置出错. handler 返回错误时不记录该消息,
+// 退避后从该消息重新投递; 没有持有消费组租约时等待其他持有者释放或过期
+func (consumer *Consumer) Run(ctx context.Context, handler func(ctx context.Context, message *Message) error) error {
+	// 消费期间登记为进行中的操作, 使 Shutdown 取消 ctx 后等待退出前的消费位置写入完成再断开连接
+	if err := consumer.offsets.client.acquire(); err != nil {
+		return err
+	}
+	defer consumer.offsets.client.release()
+	ctx, cancel := consumer.query.client.streamContext(ctx)
+	defer cancel()
+	if consumer.offsets.client != consumer.query.client {
+		var cancelOffsets context.CancelFunc
+		ctx, cancelOffsets = consumer.offsets.client.streamContext(ctx)
+		defer cancelOffsets()
+	}
+	backoff := consumer.opts.PollInterval
+	var next time.Time
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		position, ok, err := consumer.claim(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if err := sleepContext(ctx, consumer.opts.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+		messages, err := consumer.fetch(ctx, position)
+		if err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			if err := sleepContext(ctx, consumer.opts.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+		acked, progressed := position, false
+		var failed error
+		for _, message := range messages {
+			if consumer.opts.RateLimit > 0 {
+				if err := sleepContext(ctx, time.Until(next)); err != nil {
+					failed = err
+					break
+				}
+				next = time.Now().Add(time.Duration(float64(time.Second) / consumer.opts.RateLimit))
+			}
+			if failed = handler(ctx, message); failed != nil {
+				break
+			}
+			acked, progressed = message.ID, true
+		}
+		if progressed {
+			// 退出前同样记录已处理的位置, 使用独立的 ctx 避免 ctx 已结束时丢失
+			if err := consumer.commit(context.Background(), acked); err != nil {
+				return err
+			}
+		}
+		if failed == nil {
+			backoff = consumer.opts.PollInterval
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if Log != nil {
+			Log.Warn("MongoDB消息处理失败->", consumer.query.namespace(), " group:", consumer.opts.Group, " ", failed, labelSuffix(consumer.query.client.Labels()))
+		}
+		if err := sleepContext(ctx, backoff); err != nil {
+			return err
+		}
+		if backoff *= 2; backoff > consumer.opts.MaxBackoff {
+			backoff = consumer.opts.MaxBackoff
+		}
+	}
+}
+
+// Position 消费组当前的消费位置, 还没有消费过时为 nil
+func (consumer *Consumer) Position(ctx context.Context) (interface{}, error) {
+	var offset consumerOffset
+	err := consumer.offsets.do(ctx, "ConsumerPosition", func(ctx context.Context) error {
+		return consumer.offsets.Table.FindOne(ctx, bson.D{{Key: "_id", Value: consumer.opts.Group}}, &options.FindOneOptions{Comment: commentOf(ctx)}).Decode(&offset)
+	})
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	return offset.Position, err
+}
+
+// Seek 把消费组的位置设置为 position(之后从 _id 大于 position 的消息开始消费), nil 表示从头开始
+func (consumer *Consumer) Seek(ctx context.Context, position interface{}) error {
+	return consumer.offsets.do(ctx, "ConsumerSeek", func(ctx context.Context) error {
+		_, err := consumer.offsets.Table.UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: consumer.opts.Group}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "position", Value: position}}}},
+			options.Update().SetUpsert(true).SetComment(commentValue(ctx)))
+		return err
+	})
+}
+
+// claim 获取或续约消费组租约, 返回当前位置; 租约由其他进程持有时 ok 为 false
+func (consumer *Consumer) claim(ctx context.Context) (position interface{}, ok bool, err error) {
+	now := time.Now()
+	err = consumer.offsets.do(ctx, "ConsumerClaim", func(ctx context.Context) error {
+		var offset consumerOffset
+		err := consumer.offsets.Table.FindOneAndUpdate(ctx,
+			bson.D{
+				{Key: "_id", Value: consumer.opts.Group},
+				{Key: "$or", Value: bson.A{
+					bson.D{{Key: "owner", Value: consumer.owner}},
+					bson.D{{Key: "lease_until", Value: bson.D{{Key: "$lt", Value: now}}}},
+					bson.D{{Key: "lease_until", Value: bson.D{{Key: "$exists", Value: false}}}},
+				}},
+			},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "owner", Value: consumer.owner},
+				{Key: "lease_until", Value: now.Add(consumer.opts.LeaseTTL)},
+			}}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After).SetComment(commentValue(ctx)),
+		).Decode(&offset)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		position, ok = offset.Position, true
+		return nil
+	})
+	return
+}
+
+// fetch 拉取 position 之后、已过 Settle 时长的消息
+func (consumer *Consumer) fetch(ctx context.Context, position interface{}) ([]*Message, error) {
+	query := consumer.query
+	settled := primitive.NewObjectIDFromTimestamp(time.Now().Add(-consumer.opts.Settle))
+	if position != nil {
+		query = query.and(bson.E{Key: "_id", Value: bson.D{{Key: "$gt", Value: position}, {Key: "$lt", Value: settled}}})
+	} else {
+		query = query.and(bson.E{Key: "_id", Value: bson.D{{Key: "$lt", Value: settled}}})
+	}
+	docs, err := query.FindRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*Message, 0, len(docs))
+	for _, doc := range docs {
+		message := &Message{Raw: doc, query: consumer.query}
+		if id, err := doc.LookupErr("_id"); err == nil {
+			if err := unmarshalValue(id, &message.ID); err != nil {
+				return nil, err
+			}
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// commit 记录消费位置并续约, 租约已被其他进程接管时不记录. 只在 Run 中调用, Run 已登记进行中的操作,
+// 关闭期间也能写入
+func (consumer *Consumer) commit(ctx context.Context, position interface{}) error {
+	return consumer.offsets.doHeld(ctx, "ConsumerCommit", func(ctx context.Context) error {
+		result, err := consumer.offsets.Table.UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: consumer.opts.Group}, {Key: "owner", Value: consumer.owner}},
+			bson.D{{Key: "$set", Value: bson.D{
+				{Key: "position", Value: position},
+				{Key: "lease_until", Value: time.Now().Add(consumer.opts.LeaseTTL)},
+			}}},
+			options.Update().SetComment(commentValue(ctx)))
+		if err == nil && result.MatchedCount == 0 && Log != nil {
+			Log.Warn("MongoDB消费组租约已被接管->", consumer.query.namespace(), " group:", consumer.opts.Group, labelSuffix(consumer.query.client.Labels()))
+		}
+		return err
+	})
+}
+
+// sleepContext 等待 d 或 ctx 结束
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
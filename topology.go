@@ -0,0 +1,103 @@
+package mongodb
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo/description"
+)
+
+// TopologyEventType 拓扑事件类型
+type TopologyEventType string
+
+const (
+	// ServerAdded 新增节点
+	ServerAdded TopologyEventType = "server_added"
+	// ServerRemoved 节点被移除
+	ServerRemoved TopologyEventType = "server_removed"
+	// PrimaryElected 选出新的主节点, Previous 为原主节点(可能为空)
+	PrimaryElected TopologyEventType = "primary_elected"
+	// PrimaryLost 失去主节点(主节点降级或不可达), 在选出新主节点前写操作会失败
+	PrimaryLost TopologyEventType = "primary_lost"
+)
+
+// TopologyEvent 拓扑变化事件
+type TopologyEvent struct {
+	Type TopologyEventType
+	// Address 相关节点地址
+	Address  string
+	Previous string
+	Time     time.Time
+}
+
+// serverMonitor 组合 SRV 主机变化和拓扑事件的监听, 都不需要时返回 nil
+func serverMonitor(name string, config *Opt) *event.ServerMonitor {
+	var handlers []func(*event.TopologyDescriptionChangedEvent)
+	if isSRV(config.Url) {
+		handlers = append(handlers, hostsChanged(name, config))
+	}
+	if config.OnTopologyEvent != nil {
+		handlers = append(handlers, topologyChanged(name, config))
+	}
+	if len(handlers) == 0 {
+		return nil
+	}
+	return &event.ServerMonitor{
+		TopologyDescriptionChanged: func(e *event.TopologyDescriptionChangedEvent) {
+			for _, handler := range handlers {
+				handler(e)
+			}
+		},
+	}
+}
+
+// topologyChanged 对比前后两次拓扑描述, 生成节点增减和主节点切换事件
+func topologyChanged(name string, config *Opt) func(*event.TopologyDescriptionChangedEvent) {
+	return func(e *event.TopologyDescriptionChangedEvent) {
+		now := time.Now()
+		previous := serverSet(e.PreviousDescription)
+		current := serverSet(e.NewDescription)
+		var events []TopologyEvent
+		for addr := range current {
+			if _, ok := previous[addr]; !ok {
+				events = append(events, TopologyEvent{Type: ServerAdded, Address: addr, Time: now})
+			}
+		}
+		for addr := range previous {
+			if _, ok := current[addr]; !ok {
+				events = append(events, TopologyEvent{Type: ServerRemoved, Address: addr, Time: now})
+			}
+		}
+		oldPrimary := primaryOf(e.PreviousDescription)
+		newPrimary := primaryOf(e.NewDescription)
+		switch {
+		case newPrimary != "" && newPrimary != oldPrimary:
+			events = append(events, TopologyEvent{Type: PrimaryElected, Address: newPrimary, Previous: oldPrimary, Time: now})
+		case newPrimary == "" && oldPrimary != "":
+			events = append(events, TopologyEvent{Type: PrimaryLost, Address: oldPrimary, Time: now})
+		}
+		for _, ev := range events {
+			if Log != nil && (ev.Type == PrimaryElected || ev.Type == PrimaryLost) {
+				Log.Warn("MongoDB主节点变化->", name, " ", ev.Type, " ", ev.Address, labelSuffix(config.Labels))
+			}
+			config.OnTopologyEvent(ev)
+		}
+	}
+}
+
+func serverSet(topology description.Topology) map[string]description.Server {
+	servers := make(map[string]description.Server, len(topology.Servers))
+	for _, server := range topology.Servers {
+		servers[server.Addr.String()] = server
+	}
+	return servers
+}
+
+func primaryOf(topology description.Topology) string {
+	for _, server := range topology.Servers {
+		if server.Kind == description.RSPrimary {
+			return server.Addr.String()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,101 @@
+package mongodb
+
+import "context"
+
+// Tracer 链路追踪后端, 通过 Opt.Tracer 接入 ddtrace、SkyWalking 等, 内置 OpenTelemetry 实现(NewOTelTracer)
+type Tracer interface {
+	// StartSpan 以 ctx 中的 span 为父节点创建 span, 返回携带新 span 的 ctx
+	StartSpan(ctx context.Context, operation string) (context.Context, Span)
+}
+
+// Span 一次数据库操作的 span
+type Span interface {
+	SetTag(key string, value interface{})
+	Finish()
+}
+
+// startSpan 配置了 Tracer 时为操作创建 span, 否则返回 nil
+func (query *Query) startSpan(ctx context.Context, method string) (context.Context, Span) {
+	tracer := query.client.activeTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := tracer.StartSpan(ctx, "mongodb."+method)
+	span.SetTag("db.system", "mongodb")
+	span.SetTag("db.name", query.Database.Name())
+	span.SetTag("db.collection", query.Table.Name())
+	span.SetTag("db.operation", method)
+	for name, value := range query.client.Labels() {
+		span.SetTag("mongodb.label."+name, value)
+	}
+	return ctx, span
+}
+
+// finishSpan 记录错误并结束 span
+func finishSpan(span Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.SetTag("error", err)
+	}
+	span.Finish()
+}
+
+// TraceIDSpan Span 实现该接口时, 操作的 $comment 和日志中会带上追踪 ID
+type TraceIDSpan interface {
+	TraceID() string
+}
+
+type traceIDKey struct{}
+
+// WithTraceID 为 ctx 上的操作指定追踪 ID(例如请求 ID), Tracer 创建的 span 提供追踪 ID 时以 span 的为准
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext 取出 ctx 中的追踪 ID, 没有时返回空字符串
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+type spanKey struct{}
+
+// spanFromContext 当前操作的 span, 用于在操作中补充标签, 没有时返回 nil
+func spanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// traceContext 把 span 及其追踪 ID 放入 ctx
+func traceContext(ctx context.Context, span Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	if span, ok := span.(TraceIDSpan); ok {
+		if id := span.TraceID(); id != "" {
+			return WithTraceID(ctx, id)
+		}
+	}
+	return ctx
+}
+
+// commentOf 操作的 $comment, ctx 中没有追踪 ID 时为 nil
+func commentOf(ctx context.Context) *string {
+	id := TraceIDFromContext(ctx)
+	if id == "" {
+		return nil
+	}
+	comment := "trace_id:" + id
+	return &comment
+}
+
+// commentValue commentOf 的 interface{} 形式, 用于 Comment 字段为 interface{} 的参数
+func commentValue(ctx context.Context) interface{} {
+	if comment := commentOf(ctx); comment != nil {
+		return *comment
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Transaction 在新会话中执行多文档事务, fn 返回 nil 时提交, 返回错误时回滚.
+// 遇到 TransientTransactionError 时重新执行 fn, 提交结果未知时重试提交, 总时长不超过 120 秒,
+// 因此 fn 可能被执行多次, 不要在其中产生事务之外的副作用.
+// fn 中通过 WithContext(sessCtx) 让链式查询在事务中执行:
+//
+//	err := client.Transaction(ctx, func(sessCtx mongo.SessionContext) error {
+//		if _, err := client.Collection("orders").WithContext(sessCtx).InsertOne(order); err != nil {
+//			return err
+//		}
+//		_, err := client.Collection("stock").WithContext(sessCtx).Where(bson.M{"_id": order.SKU}).UpdateOneRaw(bson.M{"$inc": bson.M{"qty": -1}})
+//		return err
+//	})
+func (client *MongoDBClient) Transaction(ctx context.Context, fn func(sessCtx mongo.SessionContext) error, opts ...*options.TransactionOptions) error {
+	if client.InMaintenance() {
+		return ErrMaintenance
+	}
+	session, err := client.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+	_, err = session.WithTransaction(WithClient(ctx, client), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	}, opts...)
+	return err
+}
@@ -0,0 +1,85 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExpireAtField 文档级过期时间字段, 配合 EnsureExpireIndex 创建的 TTL 索引, 到期后由服务器删除(后台每 60 秒左右执行一次)
+const ExpireAtField = "expire_at"
+
+// EnsureExpireIndex 在 expire_at 上创建 expireAfterSeconds 为 0 的 TTL 索引, 每条文档在自己的 expire_at 时刻过期
+func (query *Query) EnsureExpireIndex(ctx context.Context) (string, error) {
+	return query.createIndex(ctx, "CreateIndex", mongo.IndexModel{
+		Keys:    bson.D{{Key: ExpireAtField, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+}
+
+// ExpiresIn 之后写入的文档在 d 之后过期, 用于密码重置令牌、验证码等临时记录
+func (query *Query) ExpiresIn(d time.Duration) *Query {
+	query = query.clone()
+	query.expireAt = func() time.Time { return time.Now().Add(d) }
+	return query
+}
+
+// ExpiresAt 之后写入的文档在 t 时刻过期
+func (query *Query) ExpiresAt(t time.Time) *Query {
+	query = query.clone()
+	query.expireAt = func() time.Time { return t }
+	return query
+}
+
+// SetExpireAt 设置满足条件的文档的过期时间, 返回修改的数量
+func (query *Query) SetExpireAt(ctx context.Context, t time.Time) (modified int64, err error) {
+	err = query.do(ctx, "SetExpireAt", func(ctx context.Context) error {
+		result, err := query.Table.UpdateMany(ctx, query.filter,
+			bson.D{{Key: "$set", Value: bson.D{{Key: ExpireAtField, Value: t}}}},
+			options.Update().SetCollation(query.collation).SetComment(commentValue(ctx)))
+		if err != nil {
+			return err
+		}
+		modified = result.ModifiedCount
+		return nil
+	})
+	return
+}
+
+// Persist 取消满足条件的文档的过期时间, 返回修改的数量
+func (query *Query) Persist(ctx context.Context) (modified int64, err error) {
+	err = query.do(ctx, "Persist", func(ctx context.Context) error {
+		result, err := query.Table.UpdateMany(ctx, query.filter,
+			bson.D{{Key: "$unset", Value: bson.D{{Key: ExpireAtField, Value: ""}}}},
+			options.Update().SetCollation(query.collation).SetComment(commentValue(ctx)))
+		if err != nil {
+			return err
+		}
+		modified = result.ModifiedCount
+		return nil
+	})
+	return
+}
+
+// applyExpiry 指定了 ExpiresIn / ExpiresAt 时为待写入的文档设置 expire_at, 文档已有该字段时保留原值
+func (query *Query) applyExpiry(documents ...interface{}) {
+	if query.expireAt == nil {
+		return
+	}
+	expireAt := query.expireAt()
+	for _, document := range documents {
+		switch doc := document.(type) {
+		case bson.M:
+			if _, ok := doc[ExpireAtField]; !ok {
+				doc[ExpireAtField] = expireAt
+			}
+		case map[string]interface{}:
+			if _, ok := doc[ExpireAtField]; !ok {
+				doc[ExpireAtField] = expireAt
+			}
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ViewDefinition 物化视图定义, 刷新时执行 Source 条件 + Pipeline, 通过 $merge 写入 Target
+type ViewDefinition struct {
+	Name     string
+	Source   *Query
+	Pipeline []bson.D
+	Target   string
+	// Merge $merge 参数, 默认按 _id 替换已存在的文档、插入新文档
+	Merge *MergeOptions
+	// Interval 定时刷新间隔, 0 不定时刷新
+	Interval time.Duration
+	// OnChange 源集合有变更时刷新, 短时间内的多次变更合并为一次
+	OnChange bool
+	// Debounce OnChange 时合并变更的等待时间, 默认 1 秒
+	Debounce time.Duration
+}
+
+// ViewStatus 物化视图的刷新状态
+type ViewStatus struct {
+	Name         string        `json:"name"`
+	Target       string        `json:"target"`
+	Refreshes    int64         `json:"refreshes"`
+	Failures     int64         `json:"failures"`
+	LastRefresh  time.Time     `json:"last_refresh"`
+	LastDuration time.Duration `json:"last_duration"`
+	LastError    string        `json:"last_error,omitempty"`
+	// Staleness 距离上次成功刷新的时间, 从未成功时为 0
+	Staleness time.Duration `json:"staleness"`
+}
+
+// ViewRefresher 按定时或变更流触发刷新已注册的物化视图
+type ViewRefresher struct {
+	mu      sync.Mutex
+	views   map[string]*materializedView
+	started bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+type materializedView struct {
+	def     ViewDefinition
+	trigger chan struct{}
+	// running 同一视图的刷新串行执行
+	running sync.Mutex
+
+	mu     sync.Mutex
+	status ViewStatus
+}
+
+// NewViewRefresher 创建物化视图刷新器
+func NewViewRefresher() *ViewRefresher {
+	return &ViewRefresher{views: make(map[string]*materializedView)}
+}
+
+// Register 注册物化视图, 需要在 Start 之前调用
+func (refresher *ViewRefresher) Register(def ViewDefinition) error {
+	if def.Name == "" || def.Source == nil || def.Target == "" {
+		return errors.New("view definition requires name, source and target")
+	}
+	if def.Debounce <= 0 {
+		def.Debounce = time.Second
+	}
+	refresher.mu.Lock()
+	defer refresher.mu.Unlock()
+	if refresher.started {
+		return errors.New("view refresher already started")
+	}
+	if _, ok := refresher.views[def.Name]; ok {
+		return fmt.Errorf("view %q already registered", def.Name)
+	}
+	refresher.views[def.Name] = &materializedView{
+		def:     def,
+		trigger: make(chan struct{}, 1),
+		status:  ViewStatus{Name: def.Name, Target: def.Target},
+	}
+	return nil
+}
+
+// Refresh 立即刷新一个视图
+func (refresher *ViewRefresher) Refresh(ctx context.Context, name string) error {
+	refresher.mu.Lock()
+	view, ok := refresher.views[name]
+	refresher.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("view %q is not registered", name)
+	}
+	return view.refresh(ctx)
+}
+
+// Start 启动定时刷新和变更流监听, 返回停止函数
+func (refresher *ViewRefresher) Start() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	refresher.mu.Lock()
+	refresher.started = true
+	refresher.cancel = cancel
+	for _, view := range refresher.views {
+		if view.def.Interval > 0 {
+			refresher.wg.Add(1)
+			go func(view *materializedView) {
+				defer refresher.wg.Done()
+				view.schedule(ctx)
+			}(view)
+		}
+		if view.def.OnChange {
+			refresher.wg.Add(2)
+			go func(view *materializedView) {
+				defer refresher.wg.Done()
+				view.watch(ctx)
+			}(view)
+			go func(view *materializedView) {
+				defer refresher.wg.Done()
+				view.debounce(ctx)
+			}(view)
+		}
+	}
+	refresher.mu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			refresher.wg.Wait()
+		})
+	}
+}
+
+// Status 所有视图的刷新状态, 按名称排序
+func (refresher *ViewRefresher) Status() []ViewStatus {
+	refresher.mu.Lock()
+	views := make([]*materializedView, 0, len(refresher.views))
+	for _, view := range refresher.views {
+		views = append(views, view)
+	}
+	refresher.mu.Unlock()
+	statuses := make([]ViewStatus, 0, len(views))
+	now := time.Now()
+	for _, view := range views {
+		view.mu.Lock()
+		status := view.status
+		view.mu.Unlock()
+		if !status.LastRefresh.IsZero() {
+			status.Staleness = now.Sub(status.LastRefresh)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// refresh 执行一次 $merge 并记录状态
+func (view *materializedView) refresh(ctx context.Context) error {
+	view.running.Lock()
+	defer view.running.Unlock()
+	merge := view.def.Merge
+	if merge == nil {
+		merge = &MergeOptions{WhenMatched: MergeReplace, WhenNotMatched: MergeInsert}
+	}
+	start := time.Now()
+	err := view.def.Source.MaterializeWith(ctx, MergeStage(view.def.Target, merge), view.def.Pipeline...)
+	view.mu.Lock()
+	defer view.mu.Unlock()
+	view.status.LastDuration = time.Since(start)
+	if err != nil {
+		view.status.Failures++
+		view.status.LastError = err.Error()
+		if Log != nil {
+			Log.Warn("MongoDB物化视图刷新失败->", view.def.Name, " ", err)
+		}
+		return err
+	}
+	view.status.Refreshes++
+	view.status.LastRefresh = start
+	view.status.LastError = ""
+	return nil
+}
+
+// schedule 按间隔定时刷新, 启动时先刷新一次
+func (view *materializedView) schedule(ctx context.Context) {
+	ticker := time.NewTicker(view.def.Interval)
+	defer ticker.Stop()
+	for {
+		view.refresh(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch 监听源集合的变更, 触发防抖刷新
+func (view *materializedView) watch(ctx context.Context) {
+	signal := func() {
+		select {
+		case view.trigger <- struct{}{}:
+		default:
+		}
+	}
+	// 变更流重建时可能错过了变更, 同样触发一次刷新
+	view.def.Source.client.Collection(view.def.Source.Table.Name()).follow(ctx, "ViewWatch",
+		func(ctx context.Context) error {
+			signal()
+			return nil
+		},
+		func(*ChangeEvent) { signal() })
+}
+
+// debounce 收到变更后等待 Debounce, 把期间的变更合并为一次刷新
+func (view *materializedView) debounce(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-view.trigger:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(view.def.Debounce):
+		}
+		// 丢弃等待期间的触发
+		select {
+		case <-view.trigger:
+		default:
+		}
+		view.refresh(ctx)
+	}
+}
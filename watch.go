@@ -0,0 +1,284 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WatchOptions 变更流参数
+type WatchOptions struct {
+	// FullDocument update 事件是否带完整文档, 默认 options.UpdateLookup
+	FullDocument options.FullDocument
+	// ResumeStore 恢复令牌存储, 打开变更流时从上次保存的位置继续, 处理完事件后保存令牌,
+	// 可以使用 CollectionCheckpoint 保存在集合中
+	ResumeStore Checkpointer
+	// SaveEvery 每处理多少个事件保存一次令牌, 默认 1; 调大可以减少写入, 重启后会重复收到未保存的事件
+	SaveEvery int
+	// StartAtOperationTime 没有已保存的令牌时从该时间点开始
+	StartAtOperationTime *primitive.Timestamp
+	BatchSize            int32
+	MaxAwaitTime         time.Duration
+}
+
+// ChangeStream 变更流迭代器, 通过 Next / Event 逐个读取事件:
+//
+//	stream, err := client.Collection("orders").Watch(ctx, nil, &mongodb.WatchOptions{
+//		ResumeStore: mongodb.CollectionCheckpoint(client.Collection("cdc_tokens"), "orders-sync"),
+//	})
+//	defer stream.Close(ctx)
+//	for stream.Next(ctx) {
+//		handle(stream.Event())
+//	}
+//	err = stream.Err()
+//
+// 上一个事件在调用 Next 时视为处理完成并保存令牌, 因此重启后至少收到一次未处理完的事件
+type ChangeStream struct {
+	client    *MongoDBClient
+	stream    *mongo.ChangeStream
+	store     Checkpointer
+	saveEvery int
+	event     ChangeEvent
+	current   bool
+	// processed 已处理但令牌尚未保存的事件数, token 为其中最后一个事件的令牌
+	processed int
+	token     bson.Raw
+	err       error
+}
+
+// Watch 监听集合变更, pipeline 为附加在链式条件 $match 之后的阶段, 可以为 nil.
+// 链式条件作用于事件字段, 如 operationType、fullDocument.status
+func (query *Query) Watch(ctx context.Context, pipeline interface{}, opts *WatchOptions) (*ChangeStream, error) {
+	stages := append(query.changePipeline(), watchStages(pipeline)...)
+	return openChangeStream(ctx, query.client, opts, func(ctx context.Context, streamOpts *options.ChangeStreamOptions) (stream *mongo.ChangeStream, err error) {
+		err = query.run(ctx, "Watch", func(ctx context.Context) error {
+			stream, err = query.Table.Watch(ctx, stages, streamOpts)
+			return err
+		})
+		return
+	})
+}
+
+// WatchDatabase 监听默认数据库中所有集合的变更
+func (client *MongoDBClient) WatchDatabase(ctx context.Context, pipeline interface{}, opts *WatchOptions) (*ChangeStream, error) {
+	if client.InMaintenance() {
+		return nil, ErrMaintenance
+	}
+	return openChangeStream(ctx, client, opts, func(ctx context.Context, streamOpts *options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+		return client.Client.Database(client.database()).Watch(ctx, watchStages(pipeline), streamOpts)
+	})
+}
+
+// WatchAll 监听整个部署(所有数据库)的变更, 需要副本集或分片集群
+func (client *MongoDBClient) WatchAll(ctx context.Context, pipeline interface{}, opts *WatchOptions) (*ChangeStream, error) {
+	if client.InMaintenance() {
+		return nil, ErrMaintenance
+	}
+	return openChangeStream(ctx, client, opts, func(ctx context.Context, streamOpts *options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+		return client.Client.Watch(ctx, watchStages(pipeline), streamOpts)
+	})
+}
+
+// watchStages 把 bson.A、[]bson.D、mongo.Pipeline 等形式的管道转换成阶段列表
+func watchStages(pipeline interface{}) []interface{} {
+	switch stages := pipeline.(type) {
+	case nil:
+		return []interface{}{}
+	case []interface{}:
+		return stages
+	case bson.A:
+		return stages
+	case []bson.D:
+		result := make([]interface{}, len(stages))
+		for i, stage := range stages {
+			result[i] = stage
+		}
+		return result
+	case mongo.Pipeline:
+		result := make([]interface{}, len(stages))
+		for i, stage := range stages {
+			result[i] = stage
+		}
+		return result
+	case bson.D:
+		return []interface{}{stages}
+	}
+	return []interface{}{pipeline}
+}
+
+// openChangeStream 加载已保存的令牌并打开变更流
+func openChangeStream(ctx context.Context, client *MongoDBClient, opts *WatchOptions, open func(ctx context.Context, streamOpts *options.ChangeStreamOptions) (*mongo.ChangeStream, error)) (*ChangeStream, error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+	streamOpts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if opts.FullDocument != "" {
+		streamOpts.SetFullDocument(opts.FullDocument)
+	}
+	if opts.BatchSize > 0 {
+		streamOpts.SetBatchSize(opts.BatchSize)
+	}
+	if opts.MaxAwaitTime > 0 {
+		streamOpts.SetMaxAwaitTime(opts.MaxAwaitTime)
+	}
+	resumed := false
+	if opts.ResumeStore != nil {
+		token, err := opts.ResumeStore.Load(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token != nil {
+			// startAfter 在集合被删除或重命名产生 invalidate 事件后也能继续
+			streamOpts.SetStartAfter(token)
+			resumed = true
+		}
+	}
+	if !resumed && opts.StartAtOperationTime != nil {
+		streamOpts.SetStartAtOperationTime(opts.StartAtOperationTime)
+	}
+	stream, err := open(ctx, streamOpts)
+	if err != nil {
+		return nil, err
+	}
+	saveEvery := opts.SaveEvery
+	if saveEvery <= 0 {
+		saveEvery = 1
+	}
+	return &ChangeStream{client: client, stream: stream, store: opts.ResumeStore, saveEvery: saveEvery}, nil
+}
+
+// Next 等待下一个事件, 变更流出错、关闭、ctx 取消或 Shutdown 开始时返回 false, 原因通过 Err 获取
+func (stream *ChangeStream) Next(ctx context.Context) bool {
+	if stream.err != nil {
+		return false
+	}
+	if stream.current {
+		stream.current = false
+		stream.processed++
+		stream.token = stream.event.ResumeToken
+		if stream.processed >= stream.saveEvery {
+			if stream.err = stream.save(ctx); stream.err != nil {
+				return false
+			}
+		}
+	}
+	waitCtx, cancel := stream.client.streamContext(ctx)
+	defer cancel()
+	if !stream.stream.Next(waitCtx) {
+		stream.err = stream.stream.Err()
+		if stream.err == nil {
+			stream.err = waitCtx.Err()
+		}
+		return false
+	}
+	stream.event = ChangeEvent{}
+	if stream.err = stream.stream.Decode(&stream.event); stream.err != nil {
+		return false
+	}
+	stream.current = true
+	return true
+}
+
+// Event 当前事件, 下一次调用 Next 后失效
+func (stream *ChangeStream) Event() *ChangeEvent {
+	return &stream.event
+}
+
+// Decode 把当前事件的完整文档解码到 v
+func (stream *ChangeStream) Decode(v interface{}) error {
+	return stream.event.DecodeFullDocument(v)
+}
+
+// Commit 立即把当前事件标记为已处理并保存令牌
+func (stream *ChangeStream) Commit(ctx context.Context) error {
+	if stream.current {
+		stream.current = false
+		stream.processed++
+		stream.token = stream.event.ResumeToken
+	}
+	return stream.save(ctx)
+}
+
+// ResumeToken 最后一个已处理事件的令牌
+func (stream *ChangeStream) ResumeToken() bson.Raw {
+	return stream.token
+}
+
+// Err 迭代结束的原因, 正常关闭时为 nil
+func (stream *ChangeStream) Err() error {
+	if errors.Is(stream.err, context.Canceled) {
+		return nil
+	}
+	return stream.err
+}
+
+// Close 保存尚未保存的令牌并关闭变更流, 当前事件(还未调用下一次 Next)不视为已处理
+func (stream *ChangeStream) Close(ctx context.Context) error {
+	err := stream.save(ctx)
+	if closeErr := stream.stream.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Each 逐个处理事件, handler 返回 nil 后事件视为已处理, 返回错误时停止且不保存该事件的令牌, 处理完毕后关闭变更流
+func (stream *ChangeStream) Each(ctx context.Context, handler func(*ChangeEvent) error) error {
+	defer stream.Close(context.Background())
+	for stream.Next(ctx) {
+		if err := handler(stream.Event()); err != nil {
+			return err
+		}
+	}
+	return stream.Err()
+}
+
+// save 保存已处理事件的令牌
+func (stream *ChangeStream) save(ctx context.Context) error {
+	if stream.store == nil || stream.processed == 0 {
+		return nil
+	}
+	if err := stream.store.Save(ctx, stream.token); err != nil {
+		return err
+	}
+	stream.processed = 0
+	return nil
+}
+
+// TypedChangeEvent 完整文档解码为 T 的变更事件, delete 事件的 Document 为 nil
+type TypedChangeEvent[T any] struct {
+	*ChangeEvent
+	Document *T
+}
+
+// Events 在后台迭代 stream, 把事件连同解码后的完整文档发送到返回的通道, 迭代结束时关闭通道并关闭变更流,
+// 结束原因通过 stream.Err 获取. 事件被接收后即视为已处理, 需要处理成功才保存令牌时使用 Each
+func Events[T any](ctx context.Context, stream *ChangeStream, buffer int) <-chan TypedChangeEvent[T] {
+	events := make(chan TypedChangeEvent[T], buffer)
+	go func() {
+		defer close(events)
+		defer stream.Close(context.Background())
+		for stream.Next(ctx) {
+			event := *stream.Event()
+			typed := TypedChangeEvent[T]{ChangeEvent: &event}
+			if len(event.FullDocument) > 0 {
+				var doc T
+				if err := unmarshal(event.FullDocument, &doc); err != nil {
+					stream.err = err
+					return
+				}
+				typed.Document = &doc
+			}
+			select {
+			case events <- typed:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events
+}
@@ -0,0 +1,139 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// 窗口边界
+const (
+	Unbounded = "unbounded"
+	Current   = "current"
+)
+
+// DocumentsWindow 按文档位置定义的窗口, lower/upper 为相对当前文档的偏移或 Unbounded / Current
+func DocumentsWindow(lower, upper interface{}) bson.D {
+	return bson.D{{Key: "documents", Value: bson.A{lower, upper}}}
+}
+
+// RangeWindow 按排序字段取值定义的窗口, unit 为空时按数值, 否则为 second、minute、day 等时间单位
+func RangeWindow(lower, upper interface{}, unit string) bson.D {
+	window := bson.D{{Key: "range", Value: bson.A{lower, upper}}}
+	if unit != "" {
+		window = append(window, bson.E{Key: "unit", Value: unit})
+	}
+	return window
+}
+
+// WindowFields $setWindowFields 阶段(MongoDB 5.0+), 在分区内按排序计算排名、移动平均、累计值等:
+//
+//	stage := mongodb.NewWindowFields().PartitionBy("region").SortBy("day", 1).
+//		MovingAverage("avg_7d", "amount", 7).
+//		CumulativeSum("total", "amount").
+//		Stage()
+type WindowFields struct {
+	partitionBy interface{}
+	sortBy      bson.D
+	output      bson.D
+}
+
+// NewWindowFields 创建 $setWindowFields 构造器
+func NewWindowFields() *WindowFields {
+	return &WindowFields{}
+}
+
+// PartitionBy 按字段分区, 不调用时整个结果为一个分区
+func (fields *WindowFields) PartitionBy(field string) *WindowFields {
+	fields.partitionBy = "$" + field
+	return fields
+}
+
+// PartitionByExpr 按表达式分区, 如按多个字段分区 bson.D{{"region", "$region"}, {"shop", "$shop"}}
+func (fields *WindowFields) PartitionByExpr(expr interface{}) *WindowFields {
+	fields.partitionBy = expr
+	return fields
+}
+
+// SortBy 分区内的排序, order 为 1 升序, -1 降序, 可多次调用追加排序字段
+func (fields *WindowFields) SortBy(field string, order int) *WindowFields {
+	fields.sortBy = append(fields.sortBy, bson.E{Key: field, Value: order})
+	return fields
+}
+
+// Output 把窗口运算 operator(如 $sum、$avg、$max) 作用于 argument 的结果写入 as 字段, window 为 nil 时为整个分区
+func (fields *WindowFields) Output(as, operator string, argument interface{}, window bson.D) *WindowFields {
+	output := bson.D{{Key: operator, Value: argument}}
+	if window != nil {
+		output = append(output, bson.E{Key: "window", Value: window})
+	}
+	fields.output = append(fields.output, bson.E{Key: as, Value: output})
+	return fields
+}
+
+// Rank 分区内的排名, 并列时跳过后续名次(1, 1, 3), 需要 SortBy
+func (fields *WindowFields) Rank(as string) *WindowFields {
+	return fields.Output(as, "$rank", bson.D{}, nil)
+}
+
+// DenseRank 分区内的排名, 并列时不跳过名次(1, 1, 2), 需要 SortBy
+func (fields *WindowFields) DenseRank(as string) *WindowFields {
+	return fields.Output(as, "$denseRank", bson.D{}, nil)
+}
+
+// RowNumber 分区内的序号, 从 1 开始, 需要 SortBy
+func (fields *WindowFields) RowNumber(as string) *WindowFields {
+	return fields.Output(as, "$documentNumber", bson.D{}, nil)
+}
+
+// MovingAverage field 字段在当前文档及之前共 n 条文档上的移动平均, 需要 SortBy
+func (fields *WindowFields) MovingAverage(as, field string, n int) *WindowFields {
+	return fields.Output(as, "$avg", "$"+field, DocumentsWindow(-(n-1), Current))
+}
+
+// CumulativeSum field 字段从分区第一条到当前文档的累计和, 需要 SortBy
+func (fields *WindowFields) CumulativeSum(as, field string) *WindowFields {
+	return fields.Output(as, "$sum", "$"+field, DocumentsWindow(Unbounded, Current))
+}
+
+// Stage 构造 $setWindowFields 阶段
+func (fields *WindowFields) Stage() bson.D {
+	stage := bson.D{}
+	if fields.partitionBy != nil {
+		stage = append(stage, bson.E{Key: "partitionBy", Value: fields.partitionBy})
+	}
+	if len(fields.sortBy) > 0 {
+		stage = append(stage, bson.E{Key: "sortBy", Value: fields.sortBy})
+	}
+	stage = append(stage, bson.E{Key: "output", Value: fields.output})
+	return bson.D{{Key: "$setWindowFields", Value: stage}}
+}
+
+// Window 对满足条件的文档计算窗口字段, 再按链式的 Sort、Skip、Limit 输出到 results(切片指针)
+func (query *Query) Window(ctx context.Context, fields *WindowFields, results interface{}) error {
+	if len(fields.output) == 0 {
+		return errors.New("window fields require at least one output")
+	}
+	pipeline := bson.A{bson.D{{Key: "$match", Value: query.filter}}, fields.Stage()}
+	if len(query.sort) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: query.sort}})
+	}
+	if query.skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: query.skip}})
+	}
+	if query.limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: query.limit}})
+	}
+	return query.do(ctx, "Window", func(ctx context.Context) error {
+		pipeline, err := query.readPipeline(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		cursor, err := query.Table.Aggregate(ctx, pipeline, query.aggregateOptions(ctx))
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, results)
+	})
+}
@@ -0,0 +1,222 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FirstOrCreate 按链式条件查找一条文档, 不存在时用 defaults 创建, 结果解码到 result.
+// 通过 findOneAndUpdate + upsert + $setOnInsert 原子完成, 并发调用不会重复创建
+func (query *Query) FirstOrCreate(ctx context.Context, defaults interface{}, result interface{}) error {
+	doc, err := toDocument(defaults)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"$setOnInsert": withoutKeys(doc, query.filter)}
+	return query.do(ctx, "FirstOrCreate", func(ctx context.Context) error {
+		single := query.Table.FindOneAndUpdate(ctx, query.filter, update, options.FindOneAndUpdate().
+			SetUpsert(true).
+			SetReturnDocument(options.After).
+			SetSort(query.sort).
+			SetProjection(query.projection(ctx)).
+			SetCollation(query.collation).
+			SetComment(commentValue(ctx)))
+		return query.decodeSingle(single, result)
+	})
+}
+
+// UpdateOrCreate 按链式条件更新一条文档, 不存在时创建, set 中的 _id 会被忽略, 返回是否为新创建
+func (query *Query) UpdateOrCreate(ctx context.Context, set interface{}) (created bool, err error) {
+	doc, err := toDocument(set)
+	if err != nil {
+		return false, err
+	}
+	fields := withoutKeys(doc, nil)
+	delete(fields, "_id")
+	if err = query.checkShardUpdate(query.filter, bson.M{"$set": fields}); err != nil {
+		return false, err
+	}
+	err = query.do(ctx, "UpdateOrCreate", func(ctx context.Context) error {
+		result, err := query.Table.UpdateOne(ctx, query.filter, bson.M{"$set": fields},
+			options.Update().SetUpsert(true).SetCollation(query.collation).SetComment(commentValue(ctx)))
+		if err != nil {
+			return err
+		}
+		created = result.UpsertedCount > 0
+		return nil
+	})
+	return
+}
+
+// Upsert 按链式条件更新一条文档, 不存在时创建. document 为结构体或 bson.M, 除 _id 外的字段通过 $set 写入,
+// _id(非零值时)只在创建时通过 $setOnInsert 写入, 条件中的字段由服务器从条件补充
+func (query *Query) Upsert(ctx context.Context, document interface{}) (result *UpdateResult, err error) {
+	doc, err := toDocument(document)
+	if err != nil {
+		return nil, err
+	}
+	fields := withoutKeys(doc, query.filter)
+	id, hasID := fields["_id"]
+	delete(fields, "_id")
+	query.applyExpiry(fields)
+	update := bson.M{}
+	if len(fields) > 0 {
+		update["$set"] = fields
+	}
+	if hasID {
+		update["$setOnInsert"] = bson.M{"_id": id}
+	}
+	if len(update) == 0 {
+		return nil, errors.New("upsert document has no fields to write")
+	}
+	if err = query.checkSize(doc); err != nil {
+		return nil, err
+	}
+	if err = query.checkShardUpdate(query.filter, update); err != nil {
+		return nil, err
+	}
+	err = query.do(ctx, "Upsert", func(ctx context.Context) error {
+		res, err := query.Table.UpdateOne(ctx, query.filter, update,
+			options.Update().SetUpsert(true).SetCollation(query.collation).SetComment(commentValue(ctx)))
+		if err != nil {
+			return err
+		}
+		result = NewUpdateResult(res)
+		return nil
+	})
+	return
+}
+
+// ReplaceOne 用 replacement 整体替换满足链式条件的第一条文档, 不在 replacement 中的字段会被删除(与 UpdateOne 的 $set 不同).
+// replacement 不能包含更新操作符, 其中的 _id 需要与原文档一致或省略; 需要不存在时插入可传 options.Replace().SetUpsert(true)
+func (query *Query) ReplaceOne(ctx context.Context, replacement interface{}, opt ...*options.ReplaceOptions) (result *UpdateResult, err error) {
+	if err = query.checkSize(replacement); err != nil {
+		return nil, err
+	}
+	if err = query.checkShardUpdate(query.filter, replacement); err != nil {
+		return nil, err
+	}
+	err = query.do(ctx, "ReplaceOne", func(ctx context.Context) error {
+		opts := append([]*options.ReplaceOptions{{Collation: query.collation, Comment: commentValue(ctx)}}, opt...)
+		res, err := query.Table.ReplaceOne(ctx, query.filter, replacement, opts...)
+		if err != nil {
+			return err
+		}
+		result = NewUpdateResult(res)
+		return nil
+	})
+	return
+}
+
+// Touch 将满足条件的文档的 updated_at 更新为服务器当前时间, 返回修改的数量. 没有 Where 条件时拒绝执行
+func (query *Query) Touch(ctx context.Context) (modified int64, err error) {
+	if len(query.where) == 0 {
+		return 0, errors.New("you can't touch all documents, it's very dangerous")
+	}
+	err = query.do(ctx, "Touch", func(ctx context.Context) error {
+		result, err := query.Table.UpdateMany(ctx, query.filter,
+			bson.M{"$currentDate": bson.M{"updated_at": true}},
+			options.Update().SetCollation(query.collation).SetComment(commentValue(ctx)))
+		if err != nil {
+			return err
+		}
+		modified = result.ModifiedCount
+		return nil
+	})
+	return
+}
+
+// FindAndModifyOptions FindOneAndUpdate / FindOneAndReplace 的参数, 排序使用链式的 Sort
+type FindAndModifyOptions struct {
+	// ReturnDocument 返回修改前(options.Before, 默认)还是修改后(options.After)的文档
+	ReturnDocument options.ReturnDocument
+	// Upsert 没有满足条件的文档时插入
+	Upsert bool
+}
+
+// findAndModifyOptions 合并可选参数, 未传时为零值
+func findAndModifyOptions(opts []*FindAndModifyOptions) FindAndModifyOptions {
+	var merged FindAndModifyOptions
+	for _, opt := range opts {
+		if opt != nil {
+			merged = *opt
+		}
+	}
+	return merged
+}
+
+// FindOneAndUpdate 按链式条件和排序原子地更新一条文档, 并把修改前(或 ReturnDocument 为 After 时修改后)的文档解码到 result.
+// update 为带更新操作符的文档或更新管道, 没有满足条件的文档时返回 mongo.ErrNoDocuments, result 为 nil 时不解码
+func (query *Query) FindOneAndUpdate(ctx context.Context, update interface{}, result interface{}, opts ...*FindAndModifyOptions) error {
+	if err := query.checkSize(update); err != nil {
+		return err
+	}
+	if err := query.checkShardUpdate(query.filter, update); err != nil {
+		return err
+	}
+	opt := findAndModifyOptions(opts)
+	return query.do(ctx, "FindOneAndUpdate", func(ctx context.Context) error {
+		single := query.Table.FindOneAndUpdate(ctx, query.filter, update, options.FindOneAndUpdate().
+			SetReturnDocument(returnDocument(opt.ReturnDocument)).
+			SetUpsert(opt.Upsert).
+			SetSort(query.sort).
+			SetProjection(query.projection(ctx)).
+			SetCollation(query.collation).
+			SetComment(commentValue(ctx)))
+		return query.decodeModified(single, result)
+	})
+}
+
+// FindOneAndReplace 按链式条件和排序原子地替换一条文档, replacement 中的 _id 需要与原文档一致或省略,
+// 返回的文档与 FindOneAndUpdate 相同
+func (query *Query) FindOneAndReplace(ctx context.Context, replacement interface{}, result interface{}, opts ...*FindAndModifyOptions) error {
+	if err := query.checkSize(replacement); err != nil {
+		return err
+	}
+	if err := query.checkShardUpdate(query.filter, replacement); err != nil {
+		return err
+	}
+	opt := findAndModifyOptions(opts)
+	return query.do(ctx, "FindOneAndReplace", func(ctx context.Context) error {
+		single := query.Table.FindOneAndReplace(ctx, query.filter, replacement, options.FindOneAndReplace().
+			SetReturnDocument(returnDocument(opt.ReturnDocument)).
+			SetUpsert(opt.Upsert).
+			SetSort(query.sort).
+			SetProjection(query.projection(ctx)).
+			SetCollation(query.collation).
+			SetComment(commentValue(ctx)))
+		return query.decodeModified(single, result)
+	})
+}
+
+// FindOneAndDelete 按链式条件和排序原子地删除一条文档并把它解码到 result, 可用于按优先级出队
+func (query *Query) FindOneAndDelete(ctx context.Context, result interface{}) error {
+	return query.do(ctx, "FindOneAndDelete", func(ctx context.Context) error {
+		single := query.Table.FindOneAndDelete(ctx, query.filter, options.FindOneAndDelete().
+			SetSort(query.sort).
+			SetProjection(query.projection(ctx)).
+			SetCollation(query.collation).
+			SetComment(commentValue(ctx)))
+		return query.decodeModified(single, result)
+	})
+}
+
+// returnDocument 未指定时使用 options.Before
+func returnDocument(value options.ReturnDocument) options.ReturnDocument {
+	if value != options.After {
+		return options.Before
+	}
+	return value
+}
+
+// decodeModified 解码 findAndModify 返回的文档, result 为 nil 时只检查错误
+func (query *Query) decodeModified(single *mongo.SingleResult, result interface{}) error {
+	if result == nil {
+		return single.Err()
+	}
+	return query.decodeSingle(single, result)
+}